@@ -1,15 +1,52 @@
 package config
 
 import (
+	"time"
+
 	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	LogLevel   string `mapstructure:"LOG_LEVEL" validate:"required,oneof=DEBUG INFO WARN ERROR"`
-	PSQLSource string `mapstructure:"PSQL_SOURCE" validate:"required"`
-	Port       string `mapstructure:"PORT" validate:"required"`
+	LogLevel    string `mapstructure:"LOG_LEVEL" validate:"required,oneof=DEBUG INFO WARN ERROR"`
+	PSQLSource  string `mapstructure:"PSQL_SOURCE" validate:"required"`
+	AutoMigrate bool   `mapstructure:"AUTO_MIGRATE"`
+	Port        string `mapstructure:"PORT" validate:"required"`
+
+	BaseCurrency string `mapstructure:"BASE_CURRENCY" validate:"required,iso4217"`
+	FXRatesFile  string `mapstructure:"FX_RATES_FILE"`
+
+	RequestTimeout  time.Duration `mapstructure:"REQUEST_TIMEOUT" validate:"gt=0"`
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT" validate:"gt=0"`
+
+	// DeletedRetention is how long soft-deleted subscriptions are kept before `purge` removes them.
+	DeletedRetention time.Duration `mapstructure:"DELETED_RETENTION" validate:"gt=0"`
+
+	// RequireIfMatch rejects subscription updates, deletions and restores that do not name the
+	// version they apply to.
+	RequireIfMatch bool `mapstructure:"REQUIRE_IF_MATCH"`
+
+	// ReminderInterval is how often the reminder scheduler runs; zero, the default, disables it.
+	// Reminders are sent ReminderDays days before a subscription renews or ends.
+	ReminderInterval time.Duration `mapstructure:"REMINDER_INTERVAL" validate:"gte=0"`
+	ReminderDays     int           `mapstructure:"REMINDER_DAYS" validate:"gte=0"`
+	// ReminderNotifier selects how reminders are delivered. The log notifier only logs them, yet they
+	// count as sent, so that a real notifier configured later does not send them again.
+	ReminderNotifier   string `mapstructure:"REMINDER_NOTIFIER" validate:"oneof=log webhook smtp"`
+	ReminderWebhookURL string `mapstructure:"REMINDER_WEBHOOK_URL" validate:"required_if=ReminderNotifier webhook,omitempty,url"`
+	SMTPAddr           string `mapstructure:"SMTP_ADDR" validate:"required_if=ReminderNotifier smtp,omitempty,hostname_port"`
+	SMTPFrom           string `mapstructure:"SMTP_FROM" validate:"required_if=ReminderNotifier smtp"`
+	SMTPTo             string `mapstructure:"SMTP_TO" validate:"required_if=ReminderNotifier smtp,omitempty,email"`
+
+	// WebhookInterval is how often the webhook dispatcher sends due deliveries; zero disables it.
+	// A failed delivery is retried after WebhookBackoff, doubled for every further failure, until
+	// it made WebhookMaxAttempts attempts.
+	WebhookInterval    time.Duration `mapstructure:"WEBHOOK_INTERVAL" validate:"gte=0"`
+	WebhookMaxAttempts int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS" validate:"gte=1"`
+	WebhookBackoff     time.Duration `mapstructure:"WEBHOOK_BACKOFF" validate:"gt=0"`
+	// WebhookTimeout bounds a single delivery request, and a single request of the reminder webhook.
+	WebhookTimeout time.Duration `mapstructure:"WEBHOOK_TIMEOUT" validate:"gt=0"`
 }
 
 func LoadConfig() *Config {
@@ -17,6 +54,24 @@ func LoadConfig() *Config {
 
 	viper.SetConfigFile(".env")
 	viper.AutomaticEnv()
+	viper.SetDefault("AUTO_MIGRATE", false)
+	viper.SetDefault("BASE_CURRENCY", "RUB")
+	viper.SetDefault("FX_RATES_FILE", "")
+	viper.SetDefault("REQUEST_TIMEOUT", "30s")
+	viper.SetDefault("SHUTDOWN_TIMEOUT", "15s")
+	viper.SetDefault("DELETED_RETENTION", "2160h")
+	viper.SetDefault("REQUIRE_IF_MATCH", false)
+	viper.SetDefault("REMINDER_INTERVAL", "0")
+	viper.SetDefault("REMINDER_DAYS", 3)
+	viper.SetDefault("REMINDER_NOTIFIER", "log")
+	viper.SetDefault("REMINDER_WEBHOOK_URL", "")
+	viper.SetDefault("SMTP_ADDR", "localhost:1025")
+	viper.SetDefault("SMTP_FROM", "reminders@localhost")
+	viper.SetDefault("SMTP_TO", "")
+	viper.SetDefault("WEBHOOK_INTERVAL", "10s")
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	viper.SetDefault("WEBHOOK_BACKOFF", "30s")
+	viper.SetDefault("WEBHOOK_TIMEOUT", "10s")
 	if err := viper.ReadInConfig(); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+const migrationsDir = "migrations"
+
+// MigrationCommands are the goose commands supported by Migrate.
+var MigrationCommands = []string{"up", "down", "status", "version"}
+
+func init() {
+	goose.SetBaseFS(migrationsFS)
+	goose.SetLogger(gooseLogger{})
+	if err := goose.SetDialect("postgres"); err != nil {
+		panic(err)
+	}
+}
+
+// Migrate runs a goose command against the migrations embedded into the binary.
+func Migrate(ctx context.Context, db *sql.DB, command string) error {
+	if err := goose.RunContext(ctx, command, db, migrationsDir); err != nil {
+		return fmt.Errorf("failed to run migration command %q: %w", command, err)
+	}
+	return nil
+}
+
+// gooseLogger routes goose output through slog.
+type gooseLogger struct{}
+
+func (gooseLogger) Printf(format string, v ...any) {
+	slog.Info(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (gooseLogger) Fatalf(format string, v ...any) {
+	slog.Error(strings.TrimSpace(fmt.Sprintf(format, v...)))
+	os.Exit(1)
+}
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE subscriptions
+    ALTER COLUMN price TYPE BIGINT USING ROUND(price::NUMERIC * 100)::BIGINT;
+COMMENT ON COLUMN subscriptions.price IS 'Monthly price in minor currency units (kopecks, cents)';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+COMMENT ON COLUMN subscriptions.price IS NULL;
+ALTER TABLE subscriptions
+    ALTER COLUMN price TYPE FLOAT USING price / 100.0;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE subscriptions ADD COLUMN billing_period TEXT NOT NULL DEFAULT 'P1M';
+COMMENT ON COLUMN subscriptions.price IS 'Price per billing period in minor currency units (kopecks, cents)';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+COMMENT ON COLUMN subscriptions.price IS 'Monthly price in minor currency units (kopecks, cents)';
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS billing_period;
+-- +goose StatementEnd
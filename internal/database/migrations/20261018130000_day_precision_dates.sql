@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE subscriptions
+    ALTER COLUMN start_date TYPE DATE,
+    ALTER COLUMN end_date TYPE DATE;
+
+-- End dates used to be stored as the first day of their month and meant the whole month.
+-- They are now inclusive days, so move them to the last day of that month.
+UPDATE subscriptions
+SET end_date = (date_trunc('month', end_date) + INTERVAL '1 month - 1 day')::DATE
+WHERE end_date IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE subscriptions
+SET end_date = date_trunc('month', end_date)::DATE
+WHERE end_date IS NOT NULL;
+
+ALTER TABLE subscriptions
+    ALTER COLUMN start_date TYPE TIMESTAMP,
+    ALTER COLUMN end_date TYPE TIMESTAMP;
+-- +goose StatementEnd
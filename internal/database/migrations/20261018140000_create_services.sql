@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE services (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    category TEXT NOT NULL DEFAULT '',
+    default_price BIGINT,
+    currency CHAR(3) NOT NULL DEFAULT 'RUB'
+);
+
+-- Canonical names and aliases, keyed by the normalized name (lower case, whitespace collapsed)
+-- so that every name resolves to a single service.
+CREATE TABLE service_names (
+    lookup_key TEXT PRIMARY KEY,
+    service_id BIGINT NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    position INT NOT NULL
+);
+
+CREATE INDEX idx_service_names_service_id ON service_names(service_id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- One service per distinct normalized name, named after its most used spelling.
+INSERT INTO services (name)
+SELECT DISTINCT ON (lookup_key) name
+FROM (
+    SELECT lower(regexp_replace(btrim(service_name), '\s+', ' ', 'g')) AS lookup_key,
+           regexp_replace(btrim(service_name), '\s+', ' ', 'g') AS name,
+           COUNT(*) AS uses
+    FROM subscriptions
+    GROUP BY 1, 2
+) spellings
+ORDER BY lookup_key, uses DESC, name;
+
+INSERT INTO service_names (lookup_key, service_id, name, position)
+SELECT lower(name), id, name, 0
+FROM services;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE subscriptions ADD COLUMN service_id BIGINT REFERENCES services(id);
+
+UPDATE subscriptions s
+SET service_id = n.service_id, service_name = n.name
+FROM service_names n
+WHERE n.lookup_key = lower(regexp_replace(btrim(s.service_name), '\s+', ' ', 'g'));
+
+ALTER TABLE subscriptions ALTER COLUMN service_id SET NOT NULL;
+
+CREATE INDEX idx_subscriptions_service_id ON subscriptions(service_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Subscriptions keep the canonical service names they were given on the way up.
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS service_id;
+DROP TABLE IF EXISTS service_names;
+DROP TABLE IF EXISTS services;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE subscriptions ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX idx_subscriptions_deleted_at ON subscriptions(deleted_at) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Without the column soft-deleted subscriptions would come back to life, so refuse to go down
+-- until they are restored or purged.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM subscriptions WHERE deleted_at IS NOT NULL) THEN
+        RAISE EXCEPTION 'subscriptions has soft-deleted rows, restore or purge them first';
+    END IF;
+END
+$$;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE subscriptions
+    ADD COLUMN trial_end DATE,
+    ADD COLUMN intro_price BIGINT,
+    ADD COLUMN intro_until DATE,
+    ADD CONSTRAINT subscriptions_intro_check CHECK ((intro_price IS NULL) = (intro_until IS NULL));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE subscriptions
+    DROP CONSTRAINT IF EXISTS subscriptions_intro_check,
+    DROP COLUMN IF EXISTS intro_until,
+    DROP COLUMN IF EXISTS intro_price,
+    DROP COLUMN IF EXISTS trial_end;
+-- +goose StatementEnd
@@ -1,13 +1,16 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log/slog"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
-func NewPSQLConnection(source string) *sql.DB {
+// NewPSQLConnection connects to the database and, if autoMigrate is set, applies pending migrations.
+func NewPSQLConnection(source string, autoMigrate bool) *sql.DB {
 	slog.Info("Connecting to PostgreSQL database")
 
 	db, err := sql.Open("pgx", source)
@@ -20,5 +23,23 @@ func NewPSQLConnection(source string) *sql.DB {
 	}
 
 	slog.Info("Successfully connected to PostgreSQL database")
+
+	if autoMigrate {
+		slog.Info("Applying pending migrations")
+		if err := Migrate(context.Background(), db, "up"); err != nil {
+			panic(err)
+		}
+	}
+
 	return db
 }
+
+// MigrationVersion returns the latest goose migration version applied to the database.
+func MigrationVersion(ctx context.Context, db *sql.DB) (int64, error) {
+	var version int64
+	query := `SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied`
+	if err := db.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get migration version: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,109 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+)
+
+// RateProvider supplies exchange rates for currency conversion in aggregates.
+type RateProvider interface {
+	// Rate returns the price of one unit of from expressed in to.
+	Rate(ctx context.Context, from, to string) (*big.Rat, error)
+}
+
+// TableRateProvider serves rates from a fixed table of prices relative to a base currency.
+type TableRateProvider struct {
+	base  string
+	rates map[string]*big.Rat
+}
+
+var _ RateProvider = (*TableRateProvider)(nil)
+
+// NewTableRateProvider returns a provider where rates[c] is the price of one unit of c in base.
+func NewTableRateProvider(base string, rates map[string]*big.Rat) *TableRateProvider {
+	table := map[string]*big.Rat{base: big.NewRat(1, 1)}
+	for currency, rate := range rates {
+		table[currency] = rate
+	}
+	return &TableRateProvider{base: base, rates: table}
+}
+
+// ratesFile is the format of the local rates table, e.g.
+//
+//	{"base": "RUB", "rates": {"USD": "81.25", "EUR": "94.10"}}
+type ratesFile struct {
+	Base  string            `json:"base"`
+	Rates map[string]string `json:"rates"`
+}
+
+// LoadTableRateProvider reads a rates table from a JSON file. Rates are decimal strings,
+// so they are loaded without floating point rounding.
+func LoadTableRateProvider(path string) (*TableRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rates file: %w", err)
+	}
+
+	var file ratesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rates file: %w", err)
+	}
+	if err := models.ValidateCurrency(file.Base); err != nil {
+		return nil, fmt.Errorf("invalid base currency in rates file: %w", err)
+	}
+
+	rates := make(map[string]*big.Rat, len(file.Rates))
+	for currency, s := range file.Rates {
+		if err := models.ValidateCurrency(currency); err != nil {
+			return nil, fmt.Errorf("invalid currency in rates file: %w", err)
+		}
+		rate, ok := new(big.Rat).SetString(s)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for %s in rates file", s, currency)
+		}
+		rates[currency] = rate
+	}
+
+	return NewTableRateProvider(file.Base, rates), nil
+}
+
+// Has reports whether the table has a rate for the currency.
+func (p *TableRateProvider) Has(currency string) bool {
+	_, ok := p.rates[currency]
+	return ok
+}
+
+// Rate fails with a ValidationError if there is no rate for to, the currency a client asked for.
+// A missing rate for from, the currency of stored subscriptions, is a gap in the table instead.
+func (p *TableRateProvider) Rate(_ context.Context, from, to string) (*big.Rat, error) {
+	fromRate, ok := p.rates[from]
+	if !ok {
+		return nil, fmt.Errorf("no exchange rate for %s in the rates table", from)
+	}
+	toRate, ok := p.rates[to]
+	if !ok {
+		return nil, merrors.NewValidationError(fmt.Sprintf("no exchange rate for %s", to))
+	}
+	return new(big.Rat).Quo(fromRate, toRate), nil
+}
+
+// Convert converts amount from one currency into another, rounding half away from zero
+// to whole minor units.
+func Convert(ctx context.Context, p RateProvider, amount models.Money, from, to string) (models.Money, error) {
+	if from == to {
+		return amount, nil
+	}
+
+	rate, err := p.Rate(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	return models.MoneyFromRat(new(big.Rat).Mul(amount.Rat(), rate)), nil
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errMissingIfMatch is returned by ifMatchVersion when the request has no If-Match header but
+// the handler requires one.
+var errMissingIfMatch = errors.New("If-Match header is required")
+
+// setETag sets the ETag header of the response to the version of the subscription.
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", fmt.Sprintf(`"%d"`, version))
+}
+
+// ifMatchVersion returns the version named by the If-Match header of the request, or zero when
+// the header is absent or "*". Only a single strong ETag as set by setETag is understood.
+func ifMatchVersion(c *gin.Context, required bool) (int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	switch header {
+	case "":
+		if required {
+			return 0, errMissingIfMatch
+		}
+		return 0, nil
+	case "*":
+		return 0, nil
+	}
+
+	tag, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		tag, ok = strings.CutSuffix(tag, `"`)
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if !ok || err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid If-Match header %q (expected a single ETag such as \"3\")", header)
+	}
+	return version, nil
+}
+
+// abortIfMatch responds to a request whose If-Match header ifMatchVersion rejected.
+func abortIfMatch(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, errMissingIfMatch) {
+		status = http.StatusPreconditionRequired
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
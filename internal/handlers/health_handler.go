@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+const readinessTimeout = 2 * time.Second
+
+type HealthHandler struct {
+	DB *sql.DB
+
+	shuttingDown atomic.Bool
+}
+
+func NewHealthHandler(db *sql.DB) *HealthHandler {
+	return &HealthHandler{DB: db}
+}
+
+// RegisterRoutes mounts the liveness and readiness probes on the given router.
+func (h *HealthHandler) RegisterRoutes(r gin.IRoutes) {
+	r.GET("/healthz", h.Liveness)
+	r.GET("/readyz", h.Readiness)
+}
+
+// SetShuttingDown makes the readiness probe fail, so the instance is taken out of
+// load balancing while in-flight requests drain.
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Liveness godoc
+// @Summary Liveness probe
+// @Description Report that the process is up
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string "ok"
+// @Router /healthz [get]
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness godoc
+// @Summary Readiness probe
+// @Description Report whether the service can serve traffic: the database is reachable and the server is not shutting down
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]any "ready, with migration_version"
+// @Failure 503 {object} map[string]string "not ready"
+// @Router /readyz [get]
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	if h.shuttingDown.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	if err := h.DB.PingContext(ctx); err != nil {
+		slog.Warn("Readiness check failed: database unreachable", "error", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "error": "database unreachable"})
+		return
+	}
+
+	version, err := database.MigrationVersion(ctx, h.DB)
+	if err != nil {
+		slog.Warn("Readiness check failed: migration version unavailable", "error", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "error": "migration version unavailable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "migration_version": version})
+}
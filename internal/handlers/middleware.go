@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestTimeout bounds the request context with the given deadline. Queries still running
+// when it expires are cancelled and the request fails with 504 Gateway Timeout. Handlers of
+// requests that take as long as the data they stream, exports and imports, opt out of it with
+// withoutRequestTimeout.
+func RequestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parent := c.Request.Context()
+		ctx, cancel := context.WithTimeout(context.WithValue(parent, untimedKey{}, parent), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+type untimedKey struct{}
+
+// untimedContext has the values of one context and the deadline and cancellation of another.
+type untimedContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c untimedContext) Value(key any) any {
+	return c.values.Value(key)
+}
+
+// withoutRequestTimeout returns the context of the request without the deadline of
+// RequestTimeout. It is still cancelled when the client goes away.
+func withoutRequestTimeout(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	parent, ok := ctx.Value(untimedKey{}).(context.Context)
+	if !ok {
+		return ctx
+	}
+	return untimedContext{Context: parent, values: ctx}
+}
+
+// Recovery is gin's recovery middleware, except that it passes http.ErrAbortHandler on to the
+// server. A handler panics with it to abort a response that is already under way, so that the
+// client sees it fail instead of ending early but normally.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, err any) {
+		if err == http.ErrAbortHandler {
+			panic(err)
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+// ActorHeader names who makes a request, for the audit log.
+const ActorHeader = "X-Actor"
+
+// AnonymousActor is recorded for requests without an ActorHeader.
+const AnonymousActor = "anonymous"
+
+// Actor stores the ActorHeader of the request in its context as the actor of the changes it makes.
+func Actor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := c.GetHeader(ActorHeader)
+		if actor == "" {
+			actor = AnonymousActor
+		}
+
+		c.Request = c.Request.WithContext(models.ContextWithActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type ServiceHandler struct {
+	Catalog *services.CatalogService
+}
+
+func NewServiceHandler(catalog *services.CatalogService) *ServiceHandler {
+	return &ServiceHandler{Catalog: catalog}
+}
+
+// RegisterRoutes mounts the service catalog endpoints on the given router group.
+func (h *ServiceHandler) RegisterRoutes(api gin.IRoutes) {
+	api.GET("/", h.ListServices)
+	api.GET("/:id", h.GetService)
+	api.POST("/", h.CreateService)
+	api.PATCH("/:id", h.UpdateService)
+	api.DELETE("/:id", h.DeleteService)
+}
+
+// CreateService godoc
+// @Summary Add a service to the catalog
+// @Description Create a catalog entry with a canonical name, optional aliases, category and default price. Names and aliases are unique across the catalog, ignoring case and extra whitespace.
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param service body models.ServiceCreateReq true "Service data"
+// @Success 201 {object} models.ServiceModel
+// @Header 201 {string} Location "/api/services/{id}"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 409 {object} map[string]string "Name already taken"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/services [post]
+func (h *ServiceHandler) CreateService(c *gin.Context) {
+	var req models.ServiceCreateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slog.Info("Creating service", "name", req.Name, "aliases", req.Aliases, "category", req.Category)
+
+	service, err := h.Catalog.Create(c.Request.Context(), &req)
+	if err != nil {
+		slog.Error("Failed to create service", "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/services/%d", service.ID))
+	c.JSON(http.StatusCreated, service)
+}
+
+// GetService godoc
+// @Summary Get service by ID
+// @Description Retrieve a catalog entry by its ID
+// @Tags services
+// @Produce json
+// @Param id path int true "Service ID"
+// @Success 200 {object} models.ServiceModel
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/services/{id} [get]
+func (h *ServiceHandler) GetService(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	service, err := h.Catalog.GetByID(c.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to get service", "id", id, "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, service)
+}
+
+// ListServices godoc
+// @Summary List services
+// @Description List the catalog, optionally only the entries in a category or known by a name or alias
+// @Tags services
+// @Produce json
+// @Param name query string false "Canonical name or alias"
+// @Param category query string false "Category"
+// @Success 200 {object} map[string][]models.ServiceModel "services"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/services [get]
+func (h *ServiceHandler) ListServices(c *gin.Context) {
+	services, err := h.Catalog.List(c.Request.Context(), models.NewServiceFilterFromURL(c.Request.URL.Query()))
+	if err != nil {
+		slog.Error("Failed to list services", "status", merrors.ErrorsToHTTP(err), "query", c.Request.URL.RawQuery, "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"services": services})
+}
+
+// UpdateService godoc
+// @Summary Update service
+// @Description Update a catalog entry by ID. Aliases, when given, replace the existing ones. Renaming a service renames its subscriptions too.
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param service body models.ServiceUpdateReq true "Updated service data"
+// @Success 200 {object} models.ServiceModel
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 409 {object} map[string]string "Name already taken"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/services/{id} [patch]
+func (h *ServiceHandler) UpdateService(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var req models.ServiceUpdateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slog.Info("Updating service", "id", id, "name", req.Name, "aliases", req.Aliases, "category", req.Category)
+
+	service, err := h.Catalog.Update(c.Request.Context(), id, &req)
+	if err != nil {
+		slog.Error("Failed to update service", "id", id, "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, service)
+}
+
+// DeleteService godoc
+// @Summary Delete service
+// @Description Delete a catalog entry by ID. Services that still have subscriptions cannot be deleted.
+// @Tags services
+// @Produce json
+// @Param id path int true "Service ID"
+// @Success 200 {object} map[string]string "Deleted"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 409 {object} map[string]string "Service has subscriptions"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/services/{id} [delete]
+func (h *ServiceHandler) DeleteService(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	slog.Info("Deleting service", "id", id)
+
+	if err := h.Catalog.Delete(c.Request.Context(), id); err != nil {
+		slog.Error("Failed to delete service", "id", id, "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Service deleted"})
+}
@@ -0,0 +1,83 @@
+package handlers_test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestServiceHandler_CRUD(t *testing.T) {
+	r := newRouter()
+
+	w := do(t, r, http.MethodPost, "/api/services/", map[string]any{"name": "Yandex Plus", "aliases": []string{"Яндекс Плюс"}, "category": "streaming", "default_price": "299"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want 201 (body %s)", w.Code, w.Body)
+	}
+	if loc := w.Header().Get("Location"); loc != "/api/services/1" {
+		t.Errorf("Location = %q, want /api/services/1", loc)
+	}
+
+	tests := []struct {
+		name       string
+		body       any
+		wantStatus int
+	}{
+		{name: "name taken", body: map[string]any{"name": "yandex plus"}, wantStatus: http.StatusConflict},
+		{name: "alias taken", body: map[string]any{"name": "Kinopoisk", "aliases": []string{"ЯНДЕКС  ПЛЮС"}}, wantStatus: http.StatusConflict},
+		{name: "duplicate alias", body: map[string]any{"name": "Kinopoisk", "aliases": []string{"kinopoisk"}}, wantStatus: http.StatusBadRequest},
+		{name: "missing name", body: map[string]any{"category": "video"}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do(t, r, http.MethodPost, "/api/services/", tt.body); w.Code != tt.wantStatus {
+				t.Errorf("POST status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+
+	w = do(t, r, http.MethodGet, "/api/services/?name=%D1%8F%D0%BD%D0%B4%D0%B5%D0%BA%D1%81+%D0%BF%D0%BB%D1%8E%D1%81", nil)
+	list := decode[map[string][]models.ServiceModel](t, w)
+	if len(list["services"]) != 1 || list["services"][0].ID != 1 {
+		t.Errorf("GET by alias = %+v, want service 1", list)
+	}
+
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Яндекс Плюс", UserID: uuid.New(), StartDate: "2025-07-17"})
+
+	w = do(t, r, http.MethodPatch, "/api/services/1", map[string]any{"name": "Yandex Plus Multi", "aliases": []string{"Yandex Plus"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d, want 200 (body %s)", w.Code, w.Body)
+	}
+	if got := decode[models.ServiceModel](t, w); got.Name != "Yandex Plus Multi" || !slices.Equal(got.Aliases, []string{"Yandex Plus"}) || got.Category != "streaming" {
+		t.Errorf("PATCH = %+v, want renamed service with replaced aliases", got)
+	}
+
+	sub := decode[map[string]any](t, do(t, r, http.MethodGet, "/api/subscriptions/1", nil))
+	if sub["service_id"] != 1.0 || sub["service_name"] != "Yandex Plus Multi" || sub["price"] != "299.00" {
+		t.Errorf("subscription after rename = %v, want the service's new name and default price", sub)
+	}
+	// The rename is a change of the subscription like any other.
+	history := decode[map[string][]models.SubscriptionEvent](t, do(t, r, http.MethodGet, "/api/subscriptions/1/history", nil))["events"]
+	if sub["version"] != 2.0 || len(history) != 2 || history[1].Action != models.ActionUpdated || history[1].After.ServiceName != "Yandex Plus Multi" {
+		t.Errorf("after rename version = %v and history = %+v, want version 2 and an update to the new name", sub["version"], history)
+	}
+
+	if w := do(t, r, http.MethodDelete, "/api/services/1", nil); w.Code != http.StatusConflict {
+		t.Errorf("DELETE in-use service status = %d, want 409", w.Code)
+	}
+	// Soft-deleted subscriptions still reference the service until they are purged.
+	do(t, r, http.MethodDelete, "/api/subscriptions/1", nil)
+	if w := do(t, r, http.MethodDelete, "/api/services/1", nil); w.Code != http.StatusConflict {
+		t.Errorf("DELETE service of a deleted subscription status = %d, want 409", w.Code)
+	}
+
+	do(t, r, http.MethodPost, "/api/services/", map[string]any{"name": "Kinopoisk"})
+	if w := do(t, r, http.MethodDelete, "/api/services/2", nil); w.Code != http.StatusOK {
+		t.Errorf("DELETE status = %d, want 200 (body %s)", w.Code, w.Body)
+	}
+	if w := do(t, r, http.MethodGet, "/api/services/2", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET after delete status = %d, want 404", w.Code)
+	}
+}
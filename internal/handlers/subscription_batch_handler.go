@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSubscriptionBatch godoc
+// @Summary Create subscriptions in bulk
+// @Description Create up to 1000 subscriptions in one transaction. In atomic mode (the default) either all of them are created or none is, and the items that did not fail themselves are reported with status 424; in partial mode every valid item is created. Each item gets the status and error or subscription a single create would have returned.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param mode query string false "Batch mode" Enums(atomic, partial)
+// @Param subscriptions body []models.SubscriptionCreateReq true "Subscriptions to create"
+// @Success 201 {object} models.BatchResp "All items created"
+// @Success 207 {object} models.BatchResp "Some items failed in partial mode"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 422 {object} models.BatchResp "An item failed in atomic mode, nothing was created"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/subscriptions/batch [post]
+func (h *SubscriptionHandler) CreateSubscriptionBatch(c *gin.Context) {
+	atomic, err := models.ParseBatchMode(c.Query("mode"))
+	if err != nil {
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	var reqs []models.SubscriptionCreateReq
+	if err := c.ShouldBindJSON(&reqs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slog.Info("Creating subscription batch", "items", len(reqs), "atomic", atomic)
+
+	subs, errs, err := h.SubService.CreateBatch(c.Request.Context(), reqs, atomic)
+	if err != nil {
+		slog.Error("Failed to create subscription batch", "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	respondBatch(c, atomic, http.StatusCreated, nil, subs, errs)
+}
+
+// UpdateSubscriptionBatch godoc
+// @Summary Update subscriptions in bulk
+// @Description Apply the same patch to up to 1000 subscriptions by ID, in one transaction. versions, when given, holds the expected version (ETag) of each subscription and is required when the server requires If-Match. Modes and results are as for the bulk create.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param mode query string false "Batch mode" Enums(atomic, partial)
+// @Param batch body models.SubscriptionBatchUpdateReq true "Subscription IDs and the patch"
+// @Success 200 {object} models.BatchResp "All items updated"
+// @Success 207 {object} models.BatchResp "Some items failed in partial mode"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 422 {object} models.BatchResp "An item failed in atomic mode, nothing was updated"
+// @Failure 428 {object} map[string]string "versions required"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/subscriptions/batch [patch]
+func (h *SubscriptionHandler) UpdateSubscriptionBatch(c *gin.Context) {
+	atomic, err := models.ParseBatchMode(c.Query("mode"))
+	if err != nil {
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	var req models.SubscriptionBatchUpdateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if h.RequireIfMatch && len(req.Versions) == 0 {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "versions are required"})
+		return
+	}
+	slog.Info("Updating subscription batch", "items", len(req.IDs), "atomic", atomic)
+
+	subs, errs, err := h.SubService.UpdateBatch(c.Request.Context(), &req, atomic)
+	if err != nil {
+		slog.Error("Failed to update subscription batch", "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	respondBatch(c, atomic, http.StatusOK, req.IDs, subs, errs)
+}
+
+// DeleteSubscriptionBatch godoc
+// @Summary Delete subscriptions in bulk
+// @Description Soft-delete up to 1000 subscriptions by ID, in one transaction. versions is as for the bulk update. Modes and results are as for the bulk create.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param mode query string false "Batch mode" Enums(atomic, partial)
+// @Param batch body models.SubscriptionBatchDeleteReq true "Subscription IDs"
+// @Success 200 {object} models.BatchResp "All items deleted"
+// @Success 207 {object} models.BatchResp "Some items failed in partial mode"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 422 {object} models.BatchResp "An item failed in atomic mode, nothing was deleted"
+// @Failure 428 {object} map[string]string "versions required"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/subscriptions/batch [delete]
+func (h *SubscriptionHandler) DeleteSubscriptionBatch(c *gin.Context) {
+	atomic, err := models.ParseBatchMode(c.Query("mode"))
+	if err != nil {
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	var req models.SubscriptionBatchDeleteReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if h.RequireIfMatch && len(req.Versions) == 0 {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "versions are required"})
+		return
+	}
+	slog.Info("Deleting subscription batch", "items", len(req.IDs), "atomic", atomic)
+
+	errs, err := h.SubService.DeleteBatch(c.Request.Context(), &req, atomic)
+	if err != nil {
+		slog.Error("Failed to delete subscription batch", "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	respondBatch(c, atomic, http.StatusOK, req.IDs, nil, errs)
+}
+
+// ImportSubscriptions godoc
+// @Summary Import subscriptions from CSV
+// @Description Create a subscription for every row of a CSV file. The header row names the columns after the fields of a subscription creation request (service_id, service_name, user_id, price, currency, billing_period, start_date, end_date), in any order. Rows are validated and stored independently: the report lists every row that was not imported by its line in the file. With dry_run=true nothing is stored. The import is not bound by the request timeout. If it stops early, the error status comes with the report up to the last stored row: rows up to line "through" that are not listed were imported.
+// @Tags subscriptions
+// @Accept text/csv
+// @Produce json
+// @Param dry_run query bool false "Only validate the rows"
+// @Param file body string true "CSV with a header row"
+// @Success 200 {object} models.ImportReport "All rows imported"
+// @Success 207 {object} models.ImportReport "Some rows failed"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 415 {object} map[string]string "Not CSV"
+// @Failure 500 {object} models.ImportReport "Import stopped early"
+// @Router /api/subscriptions/import [post]
+func (h *SubscriptionHandler) ImportSubscriptions(c *gin.Context) {
+	if contentType := c.ContentType(); contentType != "text/csv" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("unsupported content type %q, expected text/csv", contentType)})
+		return
+	}
+
+	var dryRun bool
+	if value := c.Query("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid dry_run %q", value)})
+			return
+		}
+	}
+	slog.Info("Importing subscriptions", "dry_run", dryRun)
+
+	rows, err := models.NewSubscriptionCSVReader(c.Request.Body)
+	if err != nil {
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	report, err := h.SubService.Import(withoutRequestTimeout(c), rows, dryRun)
+	if err != nil {
+		slog.Error("Failed to import subscriptions", "status", merrors.ErrorsToHTTP(err), "imported", report.Imported, "through", report.Through, "error", err)
+		c.JSON(merrors.ErrorsToHTTP(err), report)
+		return
+	}
+	slog.Info("Imported subscriptions", "dry_run", dryRun, "rows", report.Rows, "imported", report.Imported, "failed", report.Failed)
+
+	status := http.StatusOK
+	if report.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, report)
+}
+
+// respondBatch reports the outcome of every item of a batch. Items are identified by ids or, for
+// new subscriptions, by the id they got; subs, when given, holds the resulting subscriptions.
+// The response is successStatus if every item succeeded, 422 if an atomic batch failed and
+// 207 Multi-Status if only some items of a partial one did.
+func respondBatch(c *gin.Context, atomic bool, successStatus int, ids []int64, subs []*models.SubscriptionModel, errs []error) {
+	resp := &models.BatchResp{Results: make([]*models.BatchItemResult, len(errs))}
+	for i, err := range errs {
+		result := &models.BatchItemResult{Index: i, Status: successStatus}
+		if ids != nil {
+			result.ID = ids[i]
+		}
+
+		if err != nil {
+			result.Status = merrors.ErrorsToHTTP(err)
+			result.Error = merrors.ErrorToResponseString(err)
+			resp.Failed++
+		} else {
+			if subs != nil {
+				result.ID = subs[i].ID
+				result.Subscription = subs[i].ToResponse()
+			}
+			resp.Succeeded++
+		}
+		resp.Results[i] = result
+	}
+
+	status := successStatus
+	switch {
+	case resp.Failed > 0 && atomic:
+		status = http.StatusUnprocessableEntity
+	case resp.Failed > 0:
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, resp)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// exportFlushRows is how many exported rows are buffered before they are sent to the client.
+const exportFlushRows = 500
+
+// listFormat returns the format asked for by the format query parameter or, without one, by
+// the Accept header.
+func listFormat(c *gin.Context) (string, error) {
+	if format := c.Query("format"); format != "" {
+		return format, models.ValidateFormat(format)
+	}
+
+	switch c.NegotiateFormat(binding.MIMEJSON, models.ExportContentTypes[models.FormatCSV], models.ExportContentTypes[models.FormatNDJSON]) {
+	case models.ExportContentTypes[models.FormatCSV]:
+		return models.FormatCSV, nil
+	case models.ExportContentTypes[models.FormatNDJSON]:
+		return models.FormatNDJSON, nil
+	default:
+		return models.FormatJSON, nil
+	}
+}
+
+// exportSubscriptions streams every subscription matching the filter in the export format. It
+// takes as long as the export does, regardless of the request timeout. The response starts with
+// the first row, so errors before it still get a proper status; errors after it abort the
+// response, so that the client does not take a cut-short export for a complete one.
+func (h *SubscriptionHandler) exportSubscriptions(c *gin.Context, format string, filter *models.SubscriptionFilter) {
+	var (
+		enc  models.SubscriptionEncoder
+		rows int
+	)
+	start := func() error {
+		if enc != nil {
+			return nil
+		}
+		c.Header("Content-Type", models.ExportContentTypes[format])
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="subscriptions.%s"`, format))
+		c.Status(http.StatusOK)
+
+		var err error
+		enc, err = models.NewSubscriptionEncoder(format, c.Writer)
+		return err
+	}
+
+	err := h.SubService.Export(withoutRequestTimeout(c), filter, func(sub *models.SubscriptionModel) error {
+		if err := start(); err != nil {
+			return err
+		}
+		if err := enc.Write(sub); err != nil {
+			return err
+		}
+
+		if rows++; rows%exportFlushRows == 0 {
+			if err := enc.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		if err = start(); err == nil {
+			err = enc.Flush()
+		}
+	}
+
+	switch {
+	case err != nil && enc == nil:
+		slog.Error("Failed to export subscriptions", "status", merrors.ErrorsToHTTP(err), "query", c.Request.URL.RawQuery, "error", err)
+		merrors.GinReturnError(c, err)
+	case err != nil:
+		slog.Error("Subscription export cut short", "format", format, "rows", rows, "query", c.Request.URL.RawQuery, "error", err)
+		panic(http.ErrAbortHandler)
+	default:
+		slog.Info("Exported subscriptions", "format", format, "rows", rows)
+	}
+}
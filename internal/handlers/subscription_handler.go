@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -13,20 +14,44 @@ import (
 
 type SubscriptionHandler struct {
 	SubService *services.SubscriptionService
+	// RequireIfMatch makes updates, deletions and restores without an If-Match header fail with
+	// 428 Precondition Required instead of overwriting whatever version is stored.
+	RequireIfMatch bool
 }
 
 func NewSubscriptionHandler(svc *services.SubscriptionService) *SubscriptionHandler {
 	return &SubscriptionHandler{SubService: svc}
 }
 
+// RegisterRoutes mounts the subscription endpoints on the given router group.
+func (h *SubscriptionHandler) RegisterRoutes(api gin.IRoutes) {
+	api.GET("/", h.ListSubscriptions)
+	api.GET("/:id", h.GetSubscription)
+	api.GET("/:id/history", h.GetHistory)
+	api.GET("/sum", h.GetSum)
+	api.GET("/sum/monthly", h.GetMonthlySums)
+	api.POST("/", h.CreateSubscription)
+	api.POST("/batch", h.CreateSubscriptionBatch)
+	api.PATCH("/batch", h.UpdateSubscriptionBatch)
+	api.DELETE("/batch", h.DeleteSubscriptionBatch)
+	api.POST("/import", h.ImportSubscriptions)
+	api.PATCH("/:id", h.UpdateSubscription)
+	api.DELETE("/:id", h.DeleteSubscription)
+	api.POST("/:id/restore", h.RestoreSubscription)
+	api.POST("/:id/pause", h.PauseSubscription)
+	api.POST("/:id/resume", h.ResumeSubscription)
+}
+
 // CreateSubscription godoc
 // @Summary Create a new subscription
-// @Description Create a subscription with service name, price, user ID, start date, and optional end date
+// @Description Create a subscription with a service (service_id, or service_name resolved through the catalog and added to it if unknown), user ID, start date, and optional price and currency (default to the service's default price), billing period (ISO 8601 duration, default P1M) and end date
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param subscription body models.SubscriptionCreateReq true "Subscription data"
-// @Success 201 {object} map[string]string "Created"
+// @Success 201 {object} models.SubscriptionResp
+// @Header 201 {string} Location "/api/subscriptions/{id}"
+// @Header 201 {string} ETag "Version of the subscription"
 // @Failure 400 {object} map[string]string "Bad Request"
 // @Failure 500 {object} map[string]string "Internal Server Error"
 // @Router /api/subscriptions [post]
@@ -38,24 +63,28 @@ func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	slog.Info("Parsed subscription creation request", "user_id", req.UserID, "service_name", req.ServiceName, "price", req.Price, "start_date", req.StartDate, "end_date", req.EndDate)
+	slog.Info("Parsed subscription creation request", "user_id", req.UserID, "service_id", req.ServiceID, "service_name", req.ServiceName, "price", req.Price, "start_date", req.StartDate, "end_date", req.EndDate)
 
-	if err := h.SubService.Create(&req); err != nil {
+	sub, err := h.SubService.Create(c.Request.Context(), &req)
+	if err != nil {
 		slog.Error("Failed to create subscription", "status", merrors.ErrorsToHTTP(err), "error", err)
 		merrors.GinReturnError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Subscription created"})
+	c.Header("Location", fmt.Sprintf("/api/subscriptions/%d", sub.ID))
+	setETag(c, sub.Version)
+	c.JSON(http.StatusCreated, sub.ToResponse())
 }
 
 // GetSubscription godoc
 // @Summary Get subscription by ID
-// @Description Retrieve a subscription by its ID
+// @Description Retrieve a subscription by its ID. The ETag header carries its version, to be sent back in If-Match when updating or deleting it.
 // @Tags subscriptions
 // @Produce json
 // @Param id path int true "Subscription ID"
-// @Success 200 {object} models.SubscriptionModel
+// @Success 200 {object} models.SubscriptionResp
+// @Header 200 {string} ETag "Version of the subscription"
 // @Failure 400 {object} map[string]string "Bad Request"
 // @Failure 404 {object} map[string]string "Not Found"
 // @Failure 500 {object} map[string]string "Internal Server Error"
@@ -70,26 +99,63 @@ func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
 		return
 	}
 
-	sub, err := h.SubService.GetByID(id)
+	sub, err := h.SubService.GetByID(c.Request.Context(), id)
 	if err != nil {
 		slog.Error("Failed to get subscription", "id", id, "status", merrors.ErrorsToHTTP(err), "error", err)
 		merrors.GinReturnError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, sub)
+	setETag(c, sub.Version)
+	c.JSON(http.StatusOK, sub.ToResponse())
+}
+
+// GetHistory godoc
+// @Summary Get subscription history
+// @Description Retrieve the audit log of a subscription, oldest first: every creation, update and deletion with the subscription before and after it, who made it (the X-Actor request header) and when. The history outlives the subscription. Subscriptions created before the audit log existed have an empty history.
+// @Tags subscriptions
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} map[string][]models.SubscriptionEvent "events"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/subscriptions/{id}/history [get]
+func (h *SubscriptionHandler) GetHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	slog.Info("Getting subscription history", "id", idStr)
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	events, err := h.SubService.GetHistory(c.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to get subscription history", "id", id, "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events})
 }
 
 // UpdateSubscription godoc
 // @Summary Update subscription
-// @Description Update an existing subscription by ID
+// @Description Update an existing subscription by ID. With an If-Match header the update only applies if the subscription is still at that version (its ETag); without one it applies on top of the latest version, unless the server requires If-Match.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param id path int true "Subscription ID"
+// @Param If-Match header string false "ETag of the version being updated"
 // @Param subscription body models.SubscriptionUpdateReq true "Updated subscription data"
-// @Success 200 {object} map[string]string "Updated"
+// @Success 200 {object} models.SubscriptionResp
+// @Header 200 {string} ETag "Version of the subscription"
 // @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 412 {object} map[string]string "Subscription changed since the If-Match version"
+// @Failure 428 {object} map[string]string "If-Match required"
 // @Failure 500 {object} map[string]string "Internal Server Error"
 // @Router /api/subscriptions/{id} [patch]
 func (h *SubscriptionHandler) UpdateSubscription(c *gin.Context) {
@@ -101,31 +167,44 @@ func (h *SubscriptionHandler) UpdateSubscription(c *gin.Context) {
 		return
 	}
 
+	version, err := ifMatchVersion(c, h.RequireIfMatch)
+	if err != nil {
+		abortIfMatch(c, err)
+		return
+	}
+
 	var sub models.SubscriptionUpdateReq
 	if err := c.ShouldBindJSON(&sub); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	slog.Info("Parsed subscription update request", "id", id, "user_id", sub.UserID, "service_name", sub.ServiceName, "price", sub.Price, "start_date", sub.StartDate, "end_date", sub.EndDate)
+	slog.Info("Parsed subscription update request", "id", id, "version", version, "user_id", sub.UserID, "service_id", sub.ServiceID, "service_name", sub.ServiceName, "price", sub.Price, "start_date", sub.StartDate, "end_date", sub.EndDate)
 
-	if err := h.SubService.Update(id, &sub); err != nil {
+	updated, err := h.SubService.Update(c.Request.Context(), id, version, &sub)
+	if err != nil {
 		slog.Error("Failed to update subscription", "id", id, "status", merrors.ErrorsToHTTP(err), "error", err)
 		merrors.GinReturnError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Subscription updated"})
+	setETag(c, updated.Version)
+
+	c.JSON(http.StatusOK, updated.ToResponse())
 }
 
 // DeleteSubscription godoc
 // @Summary Delete subscription
-// @Description Delete a subscription by ID
+// @Description Soft-delete a subscription by ID. It is excluded from lists and sums unless include_deleted=true, can be restored, and is removed for good by the purge command once the retention period has passed. With an If-Match header the subscription is only deleted if it is still at that version.
 // @Tags subscriptions
 // @Produce json
 // @Param id path int true "Subscription ID"
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Success 200 {object} map[string]string "Deleted"
 // @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 412 {object} map[string]string "Subscription changed since the If-Match version"
+// @Failure 428 {object} map[string]string "If-Match required"
 // @Failure 500 {object} map[string]string "Internal Server Error"
 // @Router /api/subscriptions/{id} [delete]
 func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
@@ -138,7 +217,13 @@ func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
 		return
 	}
 
-	if err := h.SubService.Delete(id); err != nil {
+	version, err := ifMatchVersion(c, h.RequireIfMatch)
+	if err != nil {
+		abortIfMatch(c, err)
+		return
+	}
+
+	if err := h.SubService.Delete(c.Request.Context(), id, version); err != nil {
 		slog.Error("Failed to delete subscription", "id", id, "status", merrors.ErrorsToHTTP(err), "error", err)
 		merrors.GinReturnError(c, err)
 		return
@@ -147,16 +232,69 @@ func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Subscription deleted"})
 }
 
+// RestoreSubscription godoc
+// @Summary Restore subscription
+// @Description Undo the soft delete of a subscription
+// @Tags subscriptions
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param If-Match header string false "ETag of the deleted version being restored"
+// @Success 200 {object} models.SubscriptionResp
+// @Header 200 {string} ETag "Version of the subscription"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 412 {object} map[string]string "Subscription changed since the If-Match version"
+// @Failure 428 {object} map[string]string "If-Match required"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/subscriptions/{id}/restore [post]
+func (h *SubscriptionHandler) RestoreSubscription(c *gin.Context) {
+	idStr := c.Param("id")
+	slog.Info("Restoring subscription", "id", idStr)
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	version, err := ifMatchVersion(c, h.RequireIfMatch)
+	if err != nil {
+		abortIfMatch(c, err)
+		return
+	}
+
+	sub, err := h.SubService.Restore(c.Request.Context(), id, version)
+	if err != nil {
+		slog.Error("Failed to restore subscription", "id", id, "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	setETag(c, sub.Version)
+
+	c.JSON(http.StatusOK, sub.ToResponse())
+}
+
 // ListSubscriptions godoc
-// @Summary List subscriptions by user ID
-// @Description Retrieve all subscriptions for a user
+// @Summary List subscriptions
+// @Description Retrieve a page of subscriptions matching the filters. Pass next_cursor from the previous response as cursor to get the next page. With format=csv or format=ndjson, or an Accept header of text/csv or application/x-ndjson, every matching subscription is exported instead, streamed in the requested order; limit and cursor are ignored. CSV rows carry the price in force today but not the price history, which NDJSON includes.
 // @Tags subscriptions
 // @Produce json
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "Response format, overrides the Accept header" Enums(json, csv, ndjson)
 // @Param user_id query string false "User ID (UUID)"
-// @Param name query string false "Service name"
-// @Param from query string false "Start date (MM-YYYY)"
-// @Param till query string false "End date (MM-YYYY)"
-// @Success 200 {array} models.SubscriptionModel
+// @Param service_id query int false "Service ID in the catalog"
+// @Param service_name query string false "Service name or alias, resolved through the catalog"
+// @Param from query string false "Start date, inclusive (YYYY-MM-DD or MM-YYYY)"
+// @Param till query string false "End date, inclusive (YYYY-MM-DD or MM-YYYY)"
+// @Param include_deleted query bool false "Include soft-deleted subscriptions"
+// @Param status query string false "Only subscriptions in this status today" Enums(active, paused, ended, scheduled, trialing)
+// @Param limit query int false "Page size (1-1000, default 50)"
+// @Param cursor query string false "Opaque cursor from a previous page"
+// @Param sort query string false "Sort field; price compares amounts regardless of currency" Enums(id, price, start_date, service_name)
+// @Param order query string false "Sort order" Enums(asc, desc)
+// @Success 200 {object} models.SubscriptionPage
 // @Failure 400 {object} map[string]string "Bad Request"
 // @Failure 500 {object} map[string]string "Internal Server Error"
 // @Router /api/subscriptions [get]
@@ -169,28 +307,39 @@ func (h *SubscriptionHandler) ListSubscriptions(c *gin.Context) {
 		return
 	}
 
-	subs, err := h.SubService.GetByFilters(filter)
+	format, err := listFormat(c)
+	if err != nil {
+		merrors.GinReturnError(c, err)
+		return
+	}
+	if format != models.FormatJSON {
+		h.exportSubscriptions(c, format, filter)
+		return
+	}
+
+	page, err := h.SubService.GetByFilters(c.Request.Context(), filter)
 	if err != nil {
 		slog.Error("Failed to list subscriptions", "status", merrors.ErrorsToHTTP(err), "query", c.Request.URL.RawQuery, "error", err)
 		merrors.GinReturnError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"subscriptions": subs,
-	})
+	c.JSON(http.StatusOK, page)
 }
 
 // GetSum godoc
 // @Summary Get sum of subscription costs
-// @Description Calculate total cost of subscriptions with optional filters
+// @Description Calculate total cost of subscriptions over the [from, till] period: each subscription is charged its price on every billing date (start_date plus a multiple of billing_period) that falls inside the period. A cycle is charged in full on its billing date even if it runs past till, and a cycle billed before from is not charged; only the last cycle of a subscription cut short by its end_date is prorated. Till defaults to the current month. The total is converted into the requested currency; subtotals are per original currency.
 // @Tags subscriptions
 // @Produce json
 // @Param user_id query string false "User ID (UUID)"
-// @Param name query string false "Service name"
-// @Param from query string false "Start date (MM-YYYY)"
-// @Param till query string false "End date (MM-YYYY)"
-// @Success 200 {object} map[string]float64 "sum"
+// @Param service_id query int false "Service ID in the catalog"
+// @Param service_name query string false "Service name or alias, resolved through the catalog"
+// @Param from query string false "Start date, inclusive (YYYY-MM-DD or MM-YYYY)"
+// @Param till query string false "End date, inclusive (YYYY-MM-DD or MM-YYYY)"
+// @Param include_deleted query bool false "Include soft-deleted subscriptions"
+// @Param currency query string false "Currency of the total (ISO 4217), defaults to the base currency"
+// @Success 200 {object} models.CostSummary
 // @Failure 400 {object} map[string]string "Bad Request"
 // @Failure 500 {object} map[string]string "Internal Server Error"
 // @Router /api/subscriptions/sum [get]
@@ -203,7 +352,7 @@ func (h *SubscriptionHandler) GetSum(c *gin.Context) {
 		return
 	}
 
-	sum, err := h.SubService.GetSum(filter)
+	summary, err := h.SubService.GetSum(c.Request.Context(), filter)
 	if err != nil {
 		slog.Error("Failed to calculate subscription sum", "status", merrors.ErrorsToHTTP(err), "query", c.Request.URL.RawQuery, "error", err)
 
@@ -211,5 +360,40 @@ func (h *SubscriptionHandler) GetSum(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"sum": sum})
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetMonthlySums godoc
+// @Summary Get per-month breakdown of subscription costs
+// @Description Calculate the cost of subscriptions for every month in the [from, till] period, with a breakdown by service. Every cycle is charged in full to the month of its billing date, as in /sum. Till defaults to the current month.
+// @Tags subscriptions
+// @Produce json
+// @Param user_id query string false "User ID (UUID)"
+// @Param service_id query int false "Service ID in the catalog"
+// @Param service_name query string false "Service name or alias, resolved through the catalog"
+// @Param from query string false "Start date, inclusive (YYYY-MM-DD or MM-YYYY)"
+// @Param till query string false "End date, inclusive (YYYY-MM-DD or MM-YYYY)"
+// @Param include_deleted query bool false "Include soft-deleted subscriptions"
+// @Param currency query string false "Currency of the totals (ISO 4217), defaults to the base currency"
+// @Success 200 {object} map[string][]models.MonthlySum "months"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/subscriptions/sum/monthly [get]
+func (h *SubscriptionHandler) GetMonthlySums(c *gin.Context) {
+	slog.Info("Getting subscription monthly sums", "query", c.Request.URL.RawQuery)
+
+	filter, err := models.NewSubscriptionFilterFromURL(c.Request.URL.Query())
+	if err != nil {
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	sums, err := h.SubService.GetMonthlySums(c.Request.Context(), filter)
+	if err != nil {
+		slog.Error("Failed to calculate subscription monthly sums", "status", merrors.ErrorsToHTTP(err), "query", c.Request.URL.RawQuery, "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"months": sums})
 }
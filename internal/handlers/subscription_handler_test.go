@@ -0,0 +1,630 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/fx"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/handlers"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/repo"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func newRouter(middleware ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	subscriptions := repo.NewMemorySubscriptionRepo()
+	catalog := services.NewCatalogService(repo.NewMemoryServiceRepo(subscriptions))
+	svc := services.NewSubscriptionService(subscriptions, catalog, fx.NewTableRateProvider("RUB", nil), "RUB")
+	r := gin.New()
+	api := r.Group("/api", handlers.Actor())
+	api.Use(middleware...)
+	handlers.NewSubscriptionHandler(svc).RegisterRoutes(api.Group("/subscriptions"))
+	handlers.NewServiceHandler(catalog).RegisterRoutes(api.Group("/services"))
+	handlers.NewWebhookHandler(services.NewWebhookService(repo.NewMemoryWebhookRepo(subscriptions))).RegisterRoutes(api.Group("/webhooks"))
+	return r
+}
+
+func do(t *testing.T, r http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	return doWithHeader(t, r, method, path, nil, body)
+}
+
+func doWithHeader(t *testing.T, r http.Handler, method, path string, header http.Header, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, &buf)
+	maps.Copy(req.Header, header)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func strPtr(s string) *string { return &s }
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("failed to decode %q: %v", w.Body.String(), err)
+	}
+	return v
+}
+
+func TestSubscriptionHandler_Create(t *testing.T) {
+	r := newRouter()
+
+	tests := []struct {
+		name       string
+		body       any
+		wantStatus int
+	}{
+		{name: "valid", body: models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 400_00, StartDate: "07-2025"}, wantStatus: http.StatusCreated},
+		{name: "missing price", body: map[string]any{"service_name": "Netflix", "user_id": uuid.New(), "start_date": "07-2025"}, wantStatus: http.StatusBadRequest},
+		{name: "day precision", body: models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 400_00, StartDate: "2025-07-17", EndDate: strPtr("2025-08-16")}, wantStatus: http.StatusCreated},
+		{name: "bad start date", body: models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 400_00, StartDate: "2025-07"}, wantStatus: http.StatusBadRequest},
+		{name: "invalid day", body: models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 400_00, StartDate: "2025-02-30"}, wantStatus: http.StatusBadRequest},
+		{name: "price as string", body: map[string]any{"service_name": "Netflix", "user_id": uuid.New(), "price": "399.99", "start_date": "07-2025"}, wantStatus: http.StatusCreated},
+		{name: "price with sub-cent precision", body: map[string]any{"service_name": "Netflix", "user_id": uuid.New(), "price": 399.999, "start_date": "07-2025"}, wantStatus: http.StatusBadRequest},
+		{name: "malformed json", body: "{", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(t, r, http.MethodPost, "/api/subscriptions/", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestSubscriptionHandler_GetUpdateDelete(t *testing.T) {
+	r := newRouter()
+	w := do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 400_00, StartDate: "07-2025"})
+	if loc := w.Header().Get("Location"); loc != "/api/subscriptions/1" {
+		t.Errorf("Location = %q, want /api/subscriptions/1", loc)
+	}
+
+	if w := do(t, r, http.MethodGet, "/api/subscriptions/1", nil); w.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want 200", w.Code)
+	}
+	if w := do(t, r, http.MethodGet, "/api/subscriptions/abc", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("GET invalid id status = %d, want 400", w.Code)
+	}
+	if w := do(t, r, http.MethodGet, "/api/subscriptions/42", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET missing id status = %d, want 404", w.Code)
+	}
+
+	if w := do(t, r, http.MethodPatch, "/api/subscriptions/1", map[string]any{"price": 500}); w.Code != http.StatusOK {
+		t.Errorf("PATCH status = %d, want 200 (body %s)", w.Code, w.Body)
+	}
+	if w := do(t, r, http.MethodPatch, "/api/subscriptions/1", map[string]any{"end_date": "01-2020"}); w.Code != http.StatusBadRequest {
+		t.Errorf("PATCH end before start status = %d, want 400", w.Code)
+	}
+
+	if w := do(t, r, http.MethodDelete, "/api/subscriptions/1", nil); w.Code != http.StatusOK {
+		t.Errorf("DELETE status = %d, want 200", w.Code)
+	}
+	if w := do(t, r, http.MethodDelete, "/api/subscriptions/1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("second DELETE status = %d, want 404", w.Code)
+	}
+}
+
+func TestSubscriptionHandler_ListPaginates(t *testing.T) {
+	r := newRouter()
+	user := uuid.New()
+	for range 5 {
+		do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: user, Price: 400_00, StartDate: "07-2025"})
+	}
+
+	type page struct {
+		Subscriptions []json.RawMessage `json:"subscriptions"`
+		NextCursor    string            `json:"next_cursor"`
+		TotalCount    int64             `json:"total_count"`
+	}
+
+	path := "/api/subscriptions/?limit=2&user_id=" + user.String()
+	var count int
+	for pages := 0; ; pages++ {
+		w := do(t, r, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET status = %d, want 200 (body %s)", w.Code, w.Body)
+		}
+		p := decode[page](t, w)
+		if p.TotalCount != 5 {
+			t.Errorf("total_count = %d, want 5", p.TotalCount)
+		}
+		count += len(p.Subscriptions)
+		if p.NextCursor == "" {
+			break
+		}
+		if pages > 5 {
+			t.Fatal("pagination does not terminate")
+		}
+		path = "/api/subscriptions/?limit=2&user_id=" + user.String() + "&cursor=" + p.NextCursor
+	}
+
+	if count != 5 {
+		t.Errorf("listed %d subscriptions, want 5", count)
+	}
+
+	if w := do(t, r, http.MethodGet, "/api/subscriptions/?sort=price&cursor=garbage", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("GET bad cursor status = %d, want 400", w.Code)
+	}
+}
+
+func TestSubscriptionHandler_Sums(t *testing.T) {
+	r := newRouter()
+	user := uuid.New()
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: user, Price: 400_00, StartDate: "01-2025"})
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Spotify", UserID: user, Price: 200_00, StartDate: "02-2025", EndDate: strPtr("02-2025")})
+
+	w := do(t, r, http.MethodGet, "/api/subscriptions/sum?from=01-2025&till=03-2025&user_id="+user.String(), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /sum status = %d, want 200", w.Code)
+	}
+	if sum := decode[map[string]any](t, w)["sum"]; sum != "1400.00" {
+		t.Errorf("sum = %v, want 1400.00", sum)
+	}
+
+	w = do(t, r, http.MethodGet, "/api/subscriptions/sum/monthly?from=01-2025&till=03-2025", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /sum/monthly status = %d, want 200", w.Code)
+	}
+	months := decode[map[string][]models.MonthlySum](t, w)["months"]
+	if len(months) != 3 {
+		t.Fatalf("got %d months, want 3", len(months))
+	}
+	if m := months[1]; m.Month != "02-2025" || m.Total != 600_00 || m.ByService["Spotify"] != 200_00 {
+		t.Errorf("second month = %+v, want 02-2025 total 600 with Spotify 200", m)
+	}
+
+	if w := do(t, r, http.MethodGet, "/api/subscriptions/sum?from=05-2025&till=01-2025", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("GET /sum reversed period status = %d, want 400", w.Code)
+	}
+}
+
+func TestSubscriptionHandler_ResponseRoundTripsIntoPatch(t *testing.T) {
+	r := newRouter()
+	user := uuid.New()
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: user, Price: 400_00, StartDate: "07-2025", EndDate: strPtr("12-2025")})
+
+	w := do(t, r, http.MethodGet, "/api/subscriptions/1", nil)
+	got := decode[map[string]any](t, w)
+	want := map[string]any{"id": 1.0, "service_id": 1.0, "service_name": "Netflix", "user_id": user.String(), "price": "400.00", "currency": "RUB", "start_date": "2025-07-01", "end_date": "2025-12-31"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("GET %s = %v, want %v", k, got[k], v)
+		}
+	}
+
+	w = do(t, r, http.MethodPatch, "/api/subscriptions/1", got)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH with GET body status = %d, want 200 (body %s)", w.Code, w.Body)
+	}
+	got["version"] = 2.0 // every update bumps the version, even one that changes nothing
+	if patched := decode[map[string]any](t, w); !reflect.DeepEqual(patched, got) {
+		t.Errorf("PATCH response = %v, want %v", patched, got)
+	}
+}
+
+func TestSubscriptionHandler_IfMatch(t *testing.T) {
+	r := newRouter()
+	w := do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 400_00, StartDate: "07-2025"})
+	if etag := w.Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("POST ETag = %s, want \"1\"", etag)
+	}
+
+	etag := do(t, r, http.MethodGet, "/api/subscriptions/1", nil).Header().Get("ETag")
+	w = doWithHeader(t, r, http.MethodPatch, "/api/subscriptions/1", http.Header{"If-Match": {etag}}, map[string]any{"price": 500})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH with current ETag status = %d, want 200 (body %s)", w.Code, w.Body)
+	}
+	if got := w.Header().Get("ETag"); got != `"2"` {
+		t.Errorf("PATCH ETag = %s, want \"2\"", got)
+	}
+
+	if w := doWithHeader(t, r, http.MethodPatch, "/api/subscriptions/1", http.Header{"If-Match": {etag}}, map[string]any{"price": 600}); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("PATCH with stale ETag status = %d, want 412", w.Code)
+	}
+	if w := doWithHeader(t, r, http.MethodDelete, "/api/subscriptions/1", http.Header{"If-Match": {etag}}, nil); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with stale ETag status = %d, want 412", w.Code)
+	}
+	if w := doWithHeader(t, r, http.MethodPatch, "/api/subscriptions/1", http.Header{"If-Match": {"2"}}, map[string]any{"price": 600}); w.Code != http.StatusBadRequest {
+		t.Errorf("PATCH with malformed If-Match status = %d, want 400", w.Code)
+	}
+	if w := doWithHeader(t, r, http.MethodDelete, "/api/subscriptions/1", http.Header{"If-Match": {"*"}}, nil); w.Code != http.StatusOK {
+		t.Errorf("DELETE with If-Match * status = %d, want 200", w.Code)
+	}
+}
+
+func TestSubscriptionHandler_RequireIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	subscriptions := repo.NewMemorySubscriptionRepo()
+	catalog := services.NewCatalogService(repo.NewMemoryServiceRepo(subscriptions))
+	handler := handlers.NewSubscriptionHandler(services.NewSubscriptionService(subscriptions, catalog, fx.NewTableRateProvider("RUB", nil), "RUB"))
+	handler.RequireIfMatch = true
+	r := gin.New()
+	handler.RegisterRoutes(r.Group("/api/subscriptions"))
+
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 400_00, StartDate: "07-2025"})
+	if w := do(t, r, http.MethodPatch, "/api/subscriptions/1", map[string]any{"price": 500}); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("PATCH without If-Match status = %d, want 428", w.Code)
+	}
+	if w := do(t, r, http.MethodDelete, "/api/subscriptions/1", nil); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("DELETE without If-Match status = %d, want 428", w.Code)
+	}
+	if w := doWithHeader(t, r, http.MethodDelete, "/api/subscriptions/1", http.Header{"If-Match": {`"1"`}}, nil); w.Code != http.StatusOK {
+		t.Errorf("DELETE with If-Match status = %d, want 200", w.Code)
+	}
+
+	if w := do(t, r, http.MethodPost, "/api/subscriptions/1/restore", nil); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("restore without If-Match status = %d, want 428", w.Code)
+	}
+	if w := doWithHeader(t, r, http.MethodPost, "/api/subscriptions/1/restore", http.Header{"If-Match": {`"1"`}}, nil); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("restore with the version before deletion status = %d, want 412", w.Code)
+	}
+	if w := doWithHeader(t, r, http.MethodPost, "/api/subscriptions/1/restore", http.Header{"If-Match": {`"2"`}}, nil); w.Code != http.StatusOK {
+		t.Errorf("restore with If-Match status = %d, want 200 (body %s)", w.Code, w.Body)
+	}
+}
+
+func TestSubscriptionHandler_PauseAndResume(t *testing.T) {
+	r := newRouter()
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 400_00, StartDate: "01-2025"})
+
+	w := do(t, r, http.MethodPost, "/api/subscriptions/1/pause", map[string]any{"from": "03-2025", "until": "05-2025"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /pause status = %d, want 200 (body %s)", w.Code, w.Body)
+	}
+	if got := w.Header().Get("ETag"); got != `"2"` {
+		t.Errorf("POST /pause ETag = %s, want \"2\"", got)
+	}
+	if sum := decode[map[string]any](t, do(t, r, http.MethodGet, "/api/subscriptions/sum?from=01-2025&till=06-2025", nil))["sum"]; sum != "1200.00" {
+		t.Errorf("sum over a paused quarter = %v, want 1200.00", sum)
+	}
+	if w := do(t, r, http.MethodPost, "/api/subscriptions/1/pause", map[string]any{"from": "04-2025"}); w.Code != http.StatusConflict {
+		t.Errorf("POST /pause overlapping a pause status = %d, want 409", w.Code)
+	}
+	if w := do(t, r, http.MethodPost, "/api/subscriptions/1/resume", nil); w.Code != http.StatusConflict {
+		t.Errorf("POST /resume while active status = %d, want 409", w.Code)
+	}
+
+	w = do(t, r, http.MethodPost, "/api/subscriptions/1/pause", nil)
+	if got := decode[models.SubscriptionResp](t, w); got.Status != models.StatusPaused || len(got.Pauses) != 2 || got.Pauses[1].Until != nil {
+		t.Errorf("POST /pause from today = %+v, want an open pause", got)
+	}
+	if page := decode[map[string]any](t, do(t, r, http.MethodGet, "/api/subscriptions/?status=paused", nil)); page["total_count"] != 1.0 {
+		t.Errorf("GET ?status=paused total_count = %v, want 1", page["total_count"])
+	}
+
+	w = do(t, r, http.MethodPost, "/api/subscriptions/1/resume", nil)
+	if got := decode[models.SubscriptionResp](t, w); got.Status != models.StatusActive || len(got.Pauses) != 1 {
+		t.Errorf("POST /resume on the first paused day = %+v, want the pause cancelled", got)
+	}
+	if w := do(t, r, http.MethodGet, "/api/subscriptions/?status=sleeping", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("GET ?status=sleeping status = %d, want 400", w.Code)
+	}
+}
+
+func TestSubscriptionHandler_Batch(t *testing.T) {
+	r := newRouter()
+	user := uuid.New()
+	items := []models.SubscriptionCreateReq{
+		{ServiceName: "Netflix", UserID: user, Price: 400_00, StartDate: "01-2025"},
+		{ServiceName: "Spotify", UserID: user, StartDate: "01-2025"},
+	}
+
+	w := do(t, r, http.MethodPost, "/api/subscriptions/batch", items)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("atomic batch with an invalid item status = %d, want 422 (body %s)", w.Code, w.Body)
+	}
+	resp := decode[models.BatchResp](t, w)
+	if resp.Failed != 2 || resp.Results[0].Status != http.StatusFailedDependency || resp.Results[1].Status != http.StatusBadRequest {
+		t.Errorf("atomic batch results = %+v, want item 0 aborted and item 1 invalid", resp)
+	}
+
+	w = do(t, r, http.MethodPost, "/api/subscriptions/batch?mode=partial", items)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("partial batch status = %d, want 207 (body %s)", w.Code, w.Body)
+	}
+	resp = decode[models.BatchResp](t, w)
+	if resp.Succeeded != 1 || resp.Results[0].Status != http.StatusCreated || resp.Results[0].Subscription == nil || resp.Results[0].ID == 0 {
+		t.Errorf("partial batch results = %+v, want item 0 created", resp.Results[0])
+	}
+	id := resp.Results[0].ID
+
+	items[1].Price = 200_00
+	if w := do(t, r, http.MethodPost, "/api/subscriptions/batch", items); w.Code != http.StatusCreated {
+		t.Fatalf("valid batch status = %d, want 201 (body %s)", w.Code, w.Body)
+	}
+	if w := do(t, r, http.MethodPost, "/api/subscriptions/batch?mode=all", items); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown mode status = %d, want 400", w.Code)
+	}
+	if w := do(t, r, http.MethodPost, "/api/subscriptions/batch", []models.SubscriptionCreateReq{}); w.Code != http.StatusBadRequest {
+		t.Errorf("empty batch status = %d, want 400", w.Code)
+	}
+
+	w = do(t, r, http.MethodPatch, "/api/subscriptions/batch", map[string]any{"ids": []int64{id, 3}, "update": map[string]any{"price": "10"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("bulk PATCH status = %d, want 200 (body %s)", w.Code, w.Body)
+	}
+	if resp := decode[models.BatchResp](t, w); resp.Results[1].ID != 3 || resp.Results[1].Subscription.Price != 10_00 {
+		t.Errorf("bulk PATCH results = %+v, want subscription 3 repriced", resp.Results[1])
+	}
+
+	w = do(t, r, http.MethodDelete, "/api/subscriptions/batch?mode=partial", map[string]any{"ids": []int64{id, 42}})
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("bulk DELETE status = %d, want 207 (body %s)", w.Code, w.Body)
+	}
+	if resp := decode[models.BatchResp](t, w); resp.Results[0].Status != http.StatusOK || resp.Results[1].Status != http.StatusNotFound {
+		t.Errorf("bulk DELETE results = %+v, want the missing id to be 404", resp.Results)
+	}
+	if w := do(t, r, http.MethodGet, "/api/subscriptions/1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET deleted status = %d, want 404", w.Code)
+	}
+	if w := do(t, r, http.MethodDelete, "/api/subscriptions/batch", map[string]any{"ids": []int64{3}, "versions": []int64{}}); w.Code != http.StatusOK {
+		t.Errorf("bulk DELETE with empty versions status = %d, want 200 (body %s)", w.Code, w.Body)
+	}
+}
+
+func TestSubscriptionHandler_Import(t *testing.T) {
+	r := newRouter()
+	user := uuid.New()
+	csv := "\ufeffService_Name,user_id,price,start_date,end_date\n" +
+		"Netflix," + user.String() + ",399.99,01-2025,\n" +
+		"Spotify,not-a-uuid,199,01-2025,\n" +
+		"\"Yandex Plus\"," + user.String() + ",299,2025-02-10,01-2025\n" +
+		"Kinopoisk," + user.String() + ",,01-2025,\n" +
+		"Okko," + user.String() + ",150,2025-03-01,2025-12-31\n"
+
+	importCSV := func(query, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/subscriptions/import"+query, strings.NewReader(csv))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := importCSV("?dry_run=true", "text/csv; charset=utf-8")
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("dry run status = %d, want 207 (body %s)", w.Code, w.Body)
+	}
+	report := decode[models.ImportReport](t, w)
+	if !report.DryRun || report.Rows != 5 || report.Imported != 2 || report.Failed != 3 {
+		t.Errorf("dry run report = %+v, want 2 of 5 rows valid", report)
+	}
+	var lines []int
+	for _, e := range report.Errors {
+		lines = append(lines, e.Line)
+	}
+	if !slices.Equal(lines, []int{3, 4, 5}) {
+		t.Errorf("dry run error lines = %v, want [3 4 5]", lines)
+	}
+	if page := decode[map[string]any](t, do(t, r, http.MethodGet, "/api/subscriptions/", nil)); page["total_count"] != 0.0 {
+		t.Errorf("dry run stored %v subscriptions, want 0", page["total_count"])
+	}
+	if services := decode[map[string][]models.ServiceModel](t, do(t, r, http.MethodGet, "/api/services/", nil)); len(services["services"]) != 0 {
+		t.Errorf("dry run added %d services, want 0", len(services["services"]))
+	}
+
+	report = decode[models.ImportReport](t, importCSV("", "text/csv"))
+	if report.DryRun || report.Imported != 2 || report.Failed != 3 || report.Through != 6 || report.Error != "" {
+		t.Errorf("import report = %+v, want 2 rows imported through line 6", report)
+	}
+	if page := decode[map[string]any](t, do(t, r, http.MethodGet, "/api/subscriptions/", nil)); page["total_count"] != 2.0 {
+		t.Errorf("import stored %v subscriptions, want 2", page["total_count"])
+	}
+
+	if w := importCSV("", "application/json"); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("JSON import status = %d, want 415", w.Code)
+	}
+	csv = "service_name,user,price\n"
+	if w := importCSV("", "text/csv"); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown column status = %d, want 400", w.Code)
+	}
+}
+
+func TestSubscriptionHandler_Export(t *testing.T) {
+	r := newRouter()
+	user := uuid.New()
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: user, Price: 400_00, StartDate: "01-2025", EndDate: strPtr("12-2025")})
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Spotify", UserID: user, Price: 199_99, StartDate: "2025-02-15"})
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Okko", UserID: uuid.New(), Price: 100_00, StartDate: "01-2025"})
+
+	w := do(t, r, http.MethodGet, "/api/subscriptions/?format=csv&user_id="+user.String()+"&sort=price&limit=1", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("CSV export = %d %s, want 200 text/csv (body %s)", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+	want := "id,service_id,service_name,user_id,price,currency,billing_period,start_date,end_date,deleted_at,version,trial_end,intro_price,intro_until\n" +
+		"2,2,Spotify," + user.String() + ",199.99,RUB,P1M,2025-02-15,,,1,,,\n" +
+		"1,1,Netflix," + user.String() + ",400.00,RUB,P1M,2025-01-01,2025-12-31,,1,,,\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("CSV export =\n%s\nwant\n%s", got, want)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/subscriptions/import", strings.NewReader(w.Body.String()))
+	req.Header.Set("Content-Type", "text/csv")
+	imported := httptest.NewRecorder()
+	r.ServeHTTP(imported, req)
+	if report := decode[models.ImportReport](t, imported); report.Imported != 2 {
+		t.Errorf("import of an export = %+v, want 2 rows imported", report)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/subscriptions/?sort=id&order=desc", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("NDJSON export Content-Type = %s (body %s)", w.Header().Get("Content-Type"), w.Body)
+	}
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("NDJSON export has %d lines, want 5", len(lines))
+	}
+	var first models.SubscriptionResp
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.ID != 5 {
+		t.Errorf("first NDJSON line = %s, want subscription 5", lines[0])
+	}
+
+	if w := do(t, r, http.MethodGet, "/api/subscriptions/?format=xml", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown format status = %d, want 400", w.Code)
+	}
+	if w := do(t, r, http.MethodGet, "/api/subscriptions/?format=csv&from=02-2025&till=01-2025", nil); w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") == "text/csv" {
+		t.Errorf("export with an invalid filter = %d %s, want a 400 JSON error", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	// Text that a spreadsheet would evaluate is exported as a quoted string, and imported back as it was.
+	formulas := uuid.New()
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "=1+2", UserID: formulas, Price: 100_00, StartDate: "01-2025"})
+	w = do(t, r, http.MethodGet, "/api/subscriptions/?format=csv&user_id="+formulas.String(), nil)
+	if !strings.Contains(w.Body.String(), ",'=1+2,") {
+		t.Errorf("CSV export of a formula-like service name =\n%s\nwant it prefixed with a quote", w.Body)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/subscriptions/import", strings.NewReader(w.Body.String()))
+	req.Header.Set("Content-Type", "text/csv")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if got := decode[models.SubscriptionResp](t, do(t, r, http.MethodGet, "/api/subscriptions/7", nil)); got.ServiceName != "=1+2" {
+		t.Errorf("imported service name = %q, want =1+2", got.ServiceName)
+	}
+}
+
+func TestSubscriptionHandler_RequestTimeout(t *testing.T) {
+	r := newRouter(handlers.RequestTimeout(time.Nanosecond))
+
+	w := do(t, r, http.MethodGet, "/api/subscriptions/", nil)
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want 504 (body %s)", w.Code, w.Body)
+	}
+
+	// Exports and imports take as long as they need.
+	w = do(t, r, http.MethodGet, "/api/subscriptions/?format=csv", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv" {
+		t.Errorf("export status = %d %s, want 200 text/csv (body %s)", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/subscriptions/import", strings.NewReader("service_name,user_id,price,start_date\nNetflix,"+uuid.NewString()+",400,01-2025\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if report := decode[models.ImportReport](t, w); w.Code != http.StatusOK || report.Imported != 1 {
+		t.Errorf("import = %d %+v, want 1 row imported", w.Code, report)
+	}
+}
+
+func TestSubscriptionHandler_History(t *testing.T) {
+	r := newRouter()
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 400_00, StartDate: "2025-07-17"})
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/subscriptions/1", strings.NewReader(`{"price": "450"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(handlers.ActorHeader, "support@example.com")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d, want 200 (body %s)", w.Code, w.Body)
+	}
+
+	do(t, r, http.MethodDelete, "/api/subscriptions/1", nil)
+
+	w = do(t, r, http.MethodGet, "/api/subscriptions/1/history", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET history status = %d, want 200 (body %s)", w.Code, w.Body)
+	}
+	events := decode[map[string][]models.SubscriptionEvent](t, w)["events"]
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+
+	created, updated, deleted := events[0], events[1], events[2]
+	if created.Action != models.ActionCreated || created.Before != nil || created.After == nil || created.Actor != handlers.AnonymousActor {
+		t.Errorf("created event = %+v", created)
+	}
+	if updated.Action != models.ActionUpdated || updated.Actor != "support@example.com" ||
+		updated.Before == nil || updated.Before.Price != 400_00 || updated.After == nil || updated.After.Price != 450_00 {
+		t.Errorf("updated event = %+v, want price change 400.00 -> 450.00 by support", updated)
+	}
+	if deleted.Action != models.ActionDeleted || deleted.Before == nil || deleted.After != nil {
+		t.Errorf("deleted event = %+v", deleted)
+	}
+	if updated.CreatedAt.Before(created.CreatedAt) {
+		t.Errorf("events out of order: %v before %v", updated.CreatedAt, created.CreatedAt)
+	}
+
+	if w := do(t, r, http.MethodGet, "/api/subscriptions/2/history", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET history of unknown subscription status = %d, want 404", w.Code)
+	}
+}
+
+func TestSubscriptionHandler_SoftDeleteAndRestore(t *testing.T) {
+	r := newRouter()
+	user := uuid.New()
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: user, Price: 400_00, StartDate: "01-2025"})
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Spotify", UserID: user, Price: 200_00, StartDate: "01-2025"})
+
+	if w := do(t, r, http.MethodDelete, "/api/subscriptions/1", nil); w.Code != http.StatusOK {
+		t.Fatalf("DELETE status = %d, want 200", w.Code)
+	}
+	if w := do(t, r, http.MethodGet, "/api/subscriptions/1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET deleted status = %d, want 404", w.Code)
+	}
+	if w := do(t, r, http.MethodPatch, "/api/subscriptions/1", map[string]any{"price": "1"}); w.Code != http.StatusNotFound {
+		t.Errorf("PATCH deleted status = %d, want 404", w.Code)
+	}
+
+	sum := decode[models.CostSummary](t, do(t, r, http.MethodGet, "/api/subscriptions/sum?from=01-2025&till=01-2025", nil))
+	if sum.Sum != 200_00 {
+		t.Errorf("sum without deleted = %v, want 200.00", sum.Sum)
+	}
+	sum = decode[models.CostSummary](t, do(t, r, http.MethodGet, "/api/subscriptions/sum?from=01-2025&till=01-2025&include_deleted=true", nil))
+	if sum.Sum != 600_00 {
+		t.Errorf("sum with deleted = %v, want 600.00", sum.Sum)
+	}
+
+	page := decode[map[string]any](t, do(t, r, http.MethodGet, "/api/subscriptions/?include_deleted=true", nil))
+	if page["total_count"] != 2.0 {
+		t.Errorf("list with deleted total_count = %v, want 2", page["total_count"])
+	}
+	page = decode[map[string]any](t, do(t, r, http.MethodGet, "/api/subscriptions/", nil))
+	if page["total_count"] != 1.0 {
+		t.Errorf("list total_count = %v, want 1", page["total_count"])
+	}
+
+	w := do(t, r, http.MethodPost, "/api/subscriptions/1/restore", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("restore status = %d, want 200 (body %s)", w.Code, w.Body)
+	}
+	if restored := decode[map[string]any](t, w); restored["deleted_at"] != nil {
+		t.Errorf("restored deleted_at = %v, want none", restored["deleted_at"])
+	}
+	if w := do(t, r, http.MethodGet, "/api/subscriptions/1", nil); w.Code != http.StatusOK {
+		t.Errorf("GET restored status = %d, want 200", w.Code)
+	}
+	if w := do(t, r, http.MethodPost, "/api/subscriptions/1/restore", nil); w.Code != http.StatusNotFound {
+		t.Errorf("restore of a live subscription status = %d, want 404", w.Code)
+	}
+
+	events := decode[map[string][]models.SubscriptionEvent](t, do(t, r, http.MethodGet, "/api/subscriptions/1/history", nil))["events"]
+	if len(events) != 3 || events[2].Action != models.ActionRestored {
+		t.Errorf("history = %+v, want created, deleted, restored", events)
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// PauseSubscription godoc
+// @Summary Pause subscription
+// @Description Pause a subscription from a day (today by default) until a day or until it is resumed. Billing dates inside a pause are not charged. With an If-Match header the pause only applies if the subscription is still at that version.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param If-Match header string false "ETag of the version being paused"
+// @Param pause body models.SubscriptionPauseReq false "Pause period"
+// @Success 200 {object} models.SubscriptionResp
+// @Header 200 {string} ETag "Version of the subscription"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 409 {object} map[string]string "Already paused during that period"
+// @Failure 412 {object} map[string]string "Subscription changed since the If-Match version"
+// @Failure 428 {object} map[string]string "If-Match required"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/subscriptions/{id}/pause [post]
+func (h *SubscriptionHandler) PauseSubscription(c *gin.Context) {
+	var req models.SubscriptionPauseReq
+	id, version, ok := h.bindPauseRequest(c, &req)
+	if !ok {
+		return
+	}
+
+	slog.Info("Pausing subscription", "id", id, "version", version, "from", req.From, "until", req.Until)
+
+	sub, err := h.SubService.Pause(c.Request.Context(), id, version, &req)
+	if err != nil {
+		slog.Error("Failed to pause subscription", "id", id, "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	setETag(c, sub.Version)
+
+	c.JSON(http.StatusOK, sub.ToResponse())
+}
+
+// ResumeSubscription godoc
+// @Summary Resume subscription
+// @Description Resume a paused subscription from a day on (today by default): the pause ends the day before, or is cancelled if it has not started yet. With an If-Match header the change only applies if the subscription is still at that version.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param If-Match header string false "ETag of the version being resumed"
+// @Param resume body models.SubscriptionResumeReq false "Resume date"
+// @Success 200 {object} models.SubscriptionResp
+// @Header 200 {string} ETag "Version of the subscription"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 409 {object} map[string]string "Not paused"
+// @Failure 412 {object} map[string]string "Subscription changed since the If-Match version"
+// @Failure 428 {object} map[string]string "If-Match required"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/subscriptions/{id}/resume [post]
+func (h *SubscriptionHandler) ResumeSubscription(c *gin.Context) {
+	var req models.SubscriptionResumeReq
+	id, version, ok := h.bindPauseRequest(c, &req)
+	if !ok {
+		return
+	}
+
+	slog.Info("Resuming subscription", "id", id, "version", version, "date", req.Date)
+
+	sub, err := h.SubService.Resume(c.Request.Context(), id, version, &req)
+	if err != nil {
+		slog.Error("Failed to resume subscription", "id", id, "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	setETag(c, sub.Version)
+
+	c.JSON(http.StatusOK, sub.ToResponse())
+}
+
+// bindPauseRequest reads the id, the If-Match version and the optional JSON body of a pause or
+// resume request, and responds with an error if one of them is invalid.
+func (h *SubscriptionHandler) bindPauseRequest(c *gin.Context, req any) (int64, int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, 0, false
+	}
+
+	version, err := ifMatchVersion(c, h.RequireIfMatch)
+	if err != nil {
+		abortIfMatch(c, err)
+		return 0, 0, false
+	}
+
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, 0, false
+	}
+
+	return id, version, true
+}
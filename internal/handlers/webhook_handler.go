@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	Webhooks *services.WebhookService
+}
+
+func NewWebhookHandler(webhooks *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{Webhooks: webhooks}
+}
+
+// RegisterRoutes mounts the webhook endpoints on the given router group.
+func (h *WebhookHandler) RegisterRoutes(api gin.IRoutes) {
+	api.GET("/", h.ListWebhooks)
+	api.GET("/:id", h.GetWebhook)
+	api.POST("/", h.CreateWebhook)
+	api.DELETE("/:id", h.DeleteWebhook)
+	api.GET("/:id/deliveries", h.ListDeliveries)
+	api.POST("/:id/deliveries/:delivery_id/redeliver", h.Redeliver)
+}
+
+// CreateWebhook godoc
+// @Summary Register a webhook
+// @Description Register a URL that subscription events are posted to as JSON, optionally only the given actions. Every delivery carries the headers X-Webhook-Event, X-Webhook-Delivery, X-Webhook-Timestamp and X-Webhook-Signature: "sha256=" followed by the hex HMAC-SHA256 of "{timestamp}.{body}" keyed with the secret. Failed deliveries are retried with exponential backoff.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.WebhookCreateReq true "Webhook data"
+// @Success 201 {object} models.WebhookModel
+// @Header 201 {string} Location "/api/webhooks/{id}"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.WebhookCreateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slog.Info("Creating webhook", "url", req.URL, "events", req.Events)
+
+	webhook, err := h.Webhooks.Create(c.Request.Context(), &req)
+	if err != nil {
+		slog.Error("Failed to create webhook", "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/webhooks/%d", webhook.ID))
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// GetWebhook godoc
+// @Summary Get webhook by ID
+// @Description Retrieve a webhook by its ID. The secret is not returned.
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.WebhookModel
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	webhook, err := h.Webhooks.GetByID(c.Request.Context(), id)
+	if err != nil {
+		slog.Error("Failed to get webhook", "id", id, "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Description List the registered webhooks
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} map[string][]models.WebhookModel "webhooks"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.Webhooks.List(c.Request.Context())
+	if err != nil {
+		slog.Error("Failed to list webhooks", "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+}
+
+// DeleteWebhook godoc
+// @Summary Delete webhook
+// @Description Delete a webhook by ID along with its deliveries, including the pending ones
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} map[string]string "Deleted"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	slog.Info("Deleting webhook", "id", id)
+
+	if err := h.Webhooks.Delete(c.Request.Context(), id); err != nil {
+		slog.Error("Failed to delete webhook", "id", id, "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// ListDeliveries godoc
+// @Summary List webhook deliveries
+// @Description List the latest 100 deliveries to a webhook, newest first. Deliveries that ran out of attempts have the status "dead" and are not retried until redelivered.
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param status query string false "Delivery status" Enums(pending, delivered, dead)
+// @Success 200 {object} map[string][]models.WebhookDelivery "deliveries"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	deliveries, err := h.Webhooks.ListDeliveries(c.Request.Context(), id, c.Query("status"))
+	if err != nil {
+		slog.Error("Failed to list webhook deliveries", "id", id, "status", merrors.ErrorsToHTTP(err), "query", c.Request.URL.RawQuery, "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// Redeliver godoc
+// @Summary Redeliver a webhook delivery
+// @Description Queue a delivery to be sent again right away with a fresh set of attempts, whether it is dead, delivered or pending and due. A pending delivery that is not due yet may be in flight and is refused.
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param delivery_id path int true "Delivery ID"
+// @Success 202 {object} models.WebhookDelivery
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 409 {object} map[string]string "Delivery is not due yet"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /api/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+	slog.Info("Redelivering webhook delivery", "id", id, "delivery_id", deliveryID)
+
+	delivery, err := h.Webhooks.Redeliver(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		slog.Error("Failed to redeliver webhook delivery", "id", id, "delivery_id", deliveryID, "status", merrors.ErrorsToHTTP(err), "error", err)
+		merrors.GinReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
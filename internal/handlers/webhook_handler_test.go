@@ -0,0 +1,81 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	r := newRouter()
+	const secret = "0123456789abcdef"
+
+	tests := []struct {
+		name string
+		body any
+	}{
+		{name: "missing url", body: map[string]any{"secret": secret}},
+		{name: "not http", body: map[string]any{"url": "ftp://example.com/hooks", "secret": secret}},
+		{name: "short secret", body: map[string]any{"url": "https://example.com/hooks", "secret": "short"}},
+		{name: "unknown event", body: map[string]any{"url": "https://example.com/hooks", "secret": secret, "events": []string{"purged"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do(t, r, http.MethodPost, "/api/webhooks/", tt.body); w.Code != http.StatusBadRequest {
+				t.Errorf("POST status = %d, want 400 (body %s)", w.Code, w.Body)
+			}
+		})
+	}
+
+	w := do(t, r, http.MethodPost, "/api/webhooks/", map[string]any{"url": "https://example.com/hooks", "secret": secret, "events": []string{"created", "updated"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want 201 (body %s)", w.Code, w.Body)
+	}
+	if loc := w.Header().Get("Location"); loc != "/api/webhooks/1" {
+		t.Errorf("Location = %q, want /api/webhooks/1", loc)
+	}
+	if strings.Contains(w.Body.String(), secret) {
+		t.Errorf("POST response %s reveals the secret", w.Body)
+	}
+
+	do(t, r, http.MethodPost, "/api/subscriptions/", models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 400, StartDate: "2025-07-17"})
+	do(t, r, http.MethodPatch, "/api/subscriptions/1", map[string]any{"price": "500"})
+	do(t, r, http.MethodDelete, "/api/subscriptions/1", nil)
+
+	list := decode[map[string][]models.WebhookDelivery](t, do(t, r, http.MethodGet, "/api/webhooks/1/deliveries", nil))
+	deliveries := list["deliveries"]
+	if len(deliveries) != 2 || deliveries[0].Action != models.ActionUpdated || deliveries[1].Action != models.ActionCreated {
+		t.Fatalf("deliveries = %+v, want the update then the creation", deliveries)
+	}
+	if d := deliveries[0]; d.Status != models.DeliveryPending || d.WebhookID != 1 || !strings.Contains(string(d.Payload), `"price":"500.00"`) {
+		t.Errorf("delivery = %+v, want a pending delivery of the updated subscription", d)
+	}
+
+	if w := do(t, r, http.MethodGet, "/api/webhooks/1/deliveries?status=lost", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("GET deliveries with unknown status = %d, want 400", w.Code)
+	}
+	if list := decode[map[string][]models.WebhookDelivery](t, do(t, r, http.MethodGet, "/api/webhooks/1/deliveries?status=dead", nil)); len(list["deliveries"]) != 0 {
+		t.Errorf("dead deliveries = %+v, want none", list["deliveries"])
+	}
+
+	w = do(t, r, http.MethodPost, "/api/webhooks/1/deliveries/1/redeliver", nil)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("redeliver status = %d, want 202 (body %s)", w.Code, w.Body)
+	}
+	if got := decode[models.WebhookDelivery](t, w); got.ID != 1 || got.Status != models.DeliveryPending || got.Attempts != 0 {
+		t.Errorf("redelivered = %+v, want delivery 1 pending with no attempts", got)
+	}
+	if w := do(t, r, http.MethodPost, "/api/webhooks/1/deliveries/9/redeliver", nil); w.Code != http.StatusNotFound {
+		t.Errorf("redeliver unknown delivery status = %d, want 404", w.Code)
+	}
+
+	if w := do(t, r, http.MethodDelete, "/api/webhooks/1", nil); w.Code != http.StatusOK {
+		t.Errorf("DELETE status = %d, want 200 (body %s)", w.Code, w.Body)
+	}
+	if w := do(t, r, http.MethodGet, "/api/webhooks/1/deliveries", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET deliveries of deleted webhook status = %d, want 404", w.Code)
+	}
+}
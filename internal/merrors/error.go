@@ -1,6 +1,7 @@
 package merrors
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
@@ -13,6 +14,14 @@ func ErrorsToHTTP(err error) int {
 		return http.StatusBadRequest
 	case errors.As(err, &notFoundError):
 		return http.StatusNotFound
+	case errors.As(err, &conflictError):
+		return http.StatusConflict
+	case errors.As(err, &preconditionFailedError):
+		return http.StatusPreconditionFailed
+	case errors.As(err, &failedDependencyError):
+		return http.StatusFailedDependency
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
 	default:
 		return http.StatusInternalServerError
 	}
@@ -25,8 +34,13 @@ type ErrorJson struct {
 func ErrorToResponseString(err error) string {
 	switch {
 	case errors.As(err, &validationError) ||
-		errors.As(err, &notFoundError):
+		errors.As(err, &notFoundError) ||
+		errors.As(err, &conflictError) ||
+		errors.As(err, &preconditionFailedError) ||
+		errors.As(err, &failedDependencyError):
 		return err.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return "request timed out"
 	default:
 		return "internal server error"
 	}
@@ -60,6 +74,54 @@ func NewNotFoundErr(message string) *NotFoundError {
 	return &NotFoundError{message: message}
 }
 
+// ConflictError means the request clashes with the current state of a resource,
+// e.g. a name that is already taken or a row that is still referenced.
+type ConflictError struct {
+	message string
+}
+
+var conflictError *ConflictError
+
+func (e *ConflictError) Error() string {
+	return e.message
+}
+
+func NewConflictError(message string) *ConflictError {
+	return &ConflictError{message: message}
+}
+
+// PreconditionFailedError means a conditional request did not match the current version of a
+// resource, e.g. because someone else changed it in the meantime.
+type PreconditionFailedError struct {
+	message string
+}
+
+var preconditionFailedError *PreconditionFailedError
+
+func (e *PreconditionFailedError) Error() string {
+	return e.message
+}
+
+func NewPreconditionFailedError(message string) *PreconditionFailedError {
+	return &PreconditionFailedError{message: message}
+}
+
+// FailedDependencyError means an operation was not carried out because another one it depends
+// on failed, e.g. the items of an all-or-nothing batch.
+type FailedDependencyError struct {
+	message string
+}
+
+var failedDependencyError *FailedDependencyError
+
+func (e *FailedDependencyError) Error() string {
+	return e.message
+}
+
+func NewFailedDependencyError(message string) *FailedDependencyError {
+	return &FailedDependencyError{message: message}
+}
+
 func GinReturnError(c *gin.Context, err error) {
 	status := ErrorsToHTTP(err)
 	c.JSON(status, ErrorJson{Error: ErrorToResponseString(err)})
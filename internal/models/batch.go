@@ -0,0 +1,107 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+)
+
+// MaxBatchSize bounds the number of items in a batch request.
+const MaxBatchSize = 1000
+
+// Batch modes, chosen with the mode query parameter. An atomic batch is applied all or nothing;
+// a partial one applies every item that succeeds.
+const (
+	BatchAtomic  = "atomic"
+	BatchPartial = "partial"
+)
+
+// ParseBatchMode reports whether mode names an atomic batch. An empty mode is atomic.
+func ParseBatchMode(mode string) (bool, error) {
+	switch mode {
+	case "", BatchAtomic:
+		return true, nil
+	case BatchPartial:
+		return false, nil
+	default:
+		return false, merrors.NewValidationError(fmt.Sprintf("invalid mode %q (expected %s or %s)", mode, BatchAtomic, BatchPartial))
+	}
+}
+
+// AbortBatch fails every item of an atomic batch that has not failed by itself, after another
+// item failed and nothing was applied.
+func AbortBatch(errs []error) {
+	for i, err := range errs {
+		if err == nil {
+			errs[i] = merrors.NewFailedDependencyError("not applied: another item of the batch failed")
+		}
+	}
+}
+
+// ValidateBatchSize checks that a batch has between 1 and MaxBatchSize items.
+func ValidateBatchSize(n int) error {
+	if n == 0 || n > MaxBatchSize {
+		return merrors.NewValidationError(fmt.Sprintf("a batch must have between 1 and %d items, got %d", MaxBatchSize, n))
+	}
+	return nil
+}
+
+// SubscriptionBatchDeleteReq deletes subscriptions by id.
+type SubscriptionBatchDeleteReq struct {
+	IDs []int64 `json:"ids" validate:"unique,dive,gt=0"`
+	// Versions, when given, holds the version each subscription in IDs is expected to be at, as
+	// sent in If-Match for a single subscription. Zero matches any version.
+	Versions []int64 `json:"versions,omitempty" validate:"dive,gte=0"`
+}
+
+func (r *SubscriptionBatchDeleteReq) Validate() error {
+	if err := ValidateBatchSize(len(r.IDs)); err != nil {
+		return err
+	}
+	if err := validate.Struct(r); err != nil {
+		return merrors.NewValidationError(err.Error())
+	}
+	if len(r.Versions) != 0 && len(r.Versions) != len(r.IDs) {
+		return merrors.NewValidationError("versions must have one entry per id")
+	}
+	return nil
+}
+
+// Version returns the version the i-th subscription is expected to be at, or zero.
+func (r *SubscriptionBatchDeleteReq) Version(i int) int64 {
+	if len(r.Versions) == 0 {
+		return 0
+	}
+	return r.Versions[i]
+}
+
+// SubscriptionBatchUpdateReq applies the same patch to subscriptions by id.
+type SubscriptionBatchUpdateReq struct {
+	SubscriptionBatchDeleteReq
+	Update SubscriptionUpdateReq `json:"update"`
+}
+
+func (r *SubscriptionBatchUpdateReq) Validate() error {
+	if err := r.SubscriptionBatchDeleteReq.Validate(); err != nil {
+		return err
+	}
+	return r.Update.Validate()
+}
+
+// BatchItemResult is the outcome of one item of a batch request.
+type BatchItemResult struct {
+	// Index is the position of the item in the request.
+	Index int   `json:"index"`
+	ID    int64 `json:"id,omitempty"`
+	// Status is the HTTP status the item would have got as a request of its own.
+	Status       int               `json:"status" example:"201"`
+	Error        string            `json:"error,omitempty"`
+	Subscription *SubscriptionResp `json:"subscription,omitempty"`
+}
+
+// BatchResp reports the outcome of every item of a batch request, in request order.
+type BatchResp struct {
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Results   []*BatchItemResult `json:"results"`
+}
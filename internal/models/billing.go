@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+)
+
+// BillingPeriod is how often a subscription is charged, as an ISO 8601 duration with a
+// single unit: days, weeks, months or years, e.g. "P1M", "P1Y", "P1W", "P3M" or "P14D".
+type BillingPeriod string
+
+const DefaultBillingPeriod BillingPeriod = "P1M"
+
+const maxBillingPeriodCount = 9999
+
+func (p BillingPeriod) parse() (int, byte, error) {
+	s := string(p)
+	if len(s) < 3 || s[0] != 'P' {
+		return 0, 0, p.invalid()
+	}
+
+	unit := s[len(s)-1]
+	switch unit {
+	case 'D', 'W', 'M', 'Y':
+	default:
+		return 0, 0, p.invalid()
+	}
+
+	n, err := strconv.Atoi(s[1 : len(s)-1])
+	if err != nil || n < 1 || n > maxBillingPeriodCount || s[1] == '+' {
+		return 0, 0, p.invalid()
+	}
+	return n, unit, nil
+}
+
+func (p BillingPeriod) invalid() error {
+	return merrors.NewValidationError(fmt.Sprintf("invalid billing_period %q (expected an ISO 8601 duration such as P1M, P1Y, P1W or P14D)", string(p)))
+}
+
+func (p BillingPeriod) Validate() error {
+	_, _, err := p.parse()
+	return err
+}
+
+// BillingDate returns the k-th billing date of a subscription starting at start (the 0-th is
+// start itself). Month and year periods are anchored to the start day and clamped to the
+// last day of shorter months, so a subscription started on Jan 31 renews on Feb 28.
+func (p BillingPeriod) BillingDate(start time.Time, k int) time.Time {
+	n, unit, err := p.parse()
+	if err != nil {
+		n, unit, _ = DefaultBillingPeriod.parse()
+	}
+
+	switch unit {
+	case 'D':
+		return start.AddDate(0, 0, n*k)
+	case 'W':
+		return start.AddDate(0, 0, 7*n*k)
+	case 'Y':
+		return addMonthsClamped(start, 12*n*k)
+	default:
+		return addMonthsClamped(start, n*k)
+	}
+}
+
+// firstBillingIndex returns the index of the first billing date on or after t.
+func (p BillingPeriod) firstBillingIndex(start, t time.Time) int {
+	if !t.After(start) {
+		return 0
+	}
+
+	n, unit, err := p.parse()
+	if err != nil {
+		n, unit, _ = DefaultBillingPeriod.parse()
+	}
+
+	// Estimate the index from the elapsed time, then correct it by stepping over the exact dates.
+	var k int
+	switch unit {
+	case 'D':
+		k = int(daysBetween(start, t)) / n
+	case 'W':
+		k = int(daysBetween(start, t)) / (7 * n)
+	case 'Y':
+		k = (monthsBetween(start, t) - 1) / (12 * n)
+	default:
+		k = (monthsBetween(start, t) - 1) / n
+	}
+
+	for k > 0 && !p.BillingDate(start, k-1).Before(t) {
+		k--
+	}
+	for p.BillingDate(start, k).Before(t) {
+		k++
+	}
+	return k
+}
+
+func addMonthsClamped(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), lastDay)-1)
+}
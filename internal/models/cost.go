@@ -0,0 +1,182 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// MonthStart truncates t to the first day of its month.
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// monthsBetween returns the number of calendar months in [from, till], both inclusive.
+func monthsBetween(from, till time.Time) int {
+	return (till.Year()-from.Year())*12 + int(till.Month()-from.Month()) + 1
+}
+
+// chargeWindow returns the days of [from, till] during which the subscription is active.
+// A zero from means the period has no lower bound; till must be set, because open-ended
+// subscriptions would otherwise never stop. Both bounds are inclusive.
+func (s *SubscriptionModel) chargeWindow(from, till time.Time) (time.Time, time.Time) {
+	lower := s.StartDate
+	if from.After(lower) {
+		lower = from
+	}
+	upper := till
+	if s.EndDate != nil && s.EndDate.Before(upper) {
+		upper = *s.EndDate
+	}
+	return lower, upper
+}
+
+// Charges returns the number of billing dates of the subscription that fall inside
+// [from, till] while it is active.
+func (s *SubscriptionModel) Charges(from, till time.Time) int {
+	lower, upper := s.chargeWindow(from, till)
+
+	var count int
+	for k := s.BillingPeriod.firstBillingIndex(s.StartDate, lower); !s.BillingPeriod.BillingDate(s.StartDate, k).After(upper); k++ {
+		count++
+	}
+	return count
+}
+
+// CostInPeriod returns the total amount charged for the subscription during [from, till].
+// Every billing date charges in full the price due on it (nothing during a pause or a trial, the
+// intro price, or the price in force), except for the last cycle of a subscription whose end date cuts it
+// short: that one is prorated by the number of days it is active. The period itself does not
+// prorate: a cycle is charged in full if its billing date is inside [from, till], even if the
+// cycle runs past till, and not at all otherwise, even if it runs into the period.
+func (s *SubscriptionModel) CostInPeriod(from, till time.Time) Money {
+	lower, upper := s.chargeWindow(from, till)
+
+	var cost Money
+	k := s.BillingPeriod.firstBillingIndex(s.StartDate, lower)
+	for date := s.BillingPeriod.BillingDate(s.StartDate, k); !date.After(upper); k++ {
+		next := s.BillingPeriod.BillingDate(s.StartDate, k+1)
+		cost += s.chargeAt(date, next)
+		date = next
+	}
+	return cost
+}
+
+// chargeAt returns the amount charged on the billing date opening the cycle [date, next), at the
+// price charged on that date.
+func (s *SubscriptionModel) chargeAt(date, next time.Time) Money {
+	price := s.priceOn(date)
+	if s.EndDate == nil || !s.EndDate.Before(next.AddDate(0, 0, -1)) {
+		return price
+	}
+	return price.Prorate(daysBetween(date, *s.EndDate)+1, daysBetween(date, next))
+}
+
+// MonthlySum is the spending of a single month, broken down by service name.
+// It is accumulated in the subscriptions' own currencies and then converted with Convert.
+type MonthlySum struct {
+	Month     string           `json:"month"`
+	Currency  string           `json:"currency"`
+	Total     Money            `json:"total" swaggertype:"string" example:"1299.00"`
+	ByService map[string]Money `json:"by_service" swaggertype:"object,string"`
+	Subtotals Amounts          `json:"subtotals" swaggertype:"object,string"`
+
+	costs map[string]Amounts
+}
+
+// NewMonthlySum returns an empty MonthlySum for the month containing m. Months are labeled
+// in TimeFormat.
+func NewMonthlySum(m time.Time) *MonthlySum {
+	return &MonthlySum{
+		Month:     MonthStart(m).Format(TimeFormat),
+		ByService: make(map[string]Money),
+		Subtotals: make(Amounts),
+		costs:     make(map[string]Amounts),
+	}
+}
+
+// Add charges the subscription's cost for the days [from, till] of the month into the sum.
+func (ms *MonthlySum) Add(s *SubscriptionModel, from, till time.Time) {
+	cost := s.CostInPeriod(from, till)
+	if cost == 0 {
+		return
+	}
+	ms.Subtotals.Add(s.Currency, cost)
+	if ms.costs[s.ServiceName] == nil {
+		ms.costs[s.ServiceName] = make(Amounts)
+	}
+	ms.costs[s.ServiceName].Add(s.Currency, cost)
+}
+
+// Convert fills Total and ByService with the month's costs expressed in currency.
+func (ms *MonthlySum) Convert(currency string, convert ConvertFunc) error {
+	ms.Currency = currency
+	ms.Total = 0
+	for service, amounts := range ms.costs {
+		cost, err := amounts.Convert(currency, convert)
+		if err != nil {
+			return err
+		}
+		ms.ByService[service] = cost
+		ms.Total += cost
+	}
+	return nil
+}
+
+// TotalCost returns the total cost of the subscriptions over [from, till], per currency. It stops
+// with the error of ctx once ctx is done.
+func TotalCost(ctx context.Context, subscriptions []*SubscriptionModel, from, till time.Time) (Amounts, error) {
+	sum := make(Amounts)
+	for _, s := range subscriptions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if cost := s.CostInPeriod(from, till); cost != 0 {
+			sum.Add(s.Currency, cost)
+		}
+	}
+	return sum, nil
+}
+
+// NewMonthlySums returns the spending of the subscriptions for every month in [from, till].
+// When from is zero, the series starts at the earliest subscription. The first and the last
+// month only cover the days inside [from, till]. It stops with the error of ctx once ctx is done.
+func NewMonthlySums(ctx context.Context, subscriptions []*SubscriptionModel, from, till time.Time) ([]*MonthlySum, error) {
+	if from.IsZero() {
+		for _, s := range subscriptions {
+			if from.IsZero() || s.StartDate.Before(from) {
+				from = s.StartDate
+			}
+		}
+		if from.IsZero() {
+			return []*MonthlySum{}, nil
+		}
+	}
+
+	sums := []*MonthlySum{}
+	for m := MonthStart(from); !m.After(till); m = m.AddDate(0, 1, 0) {
+		lower, upper := m, MonthEnd(m)
+		if from.After(lower) {
+			lower = from
+		}
+		if till.Before(upper) {
+			upper = till
+		}
+		monthSum := NewMonthlySum(m)
+		for _, s := range subscriptions {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			monthSum.Add(s, lower, upper)
+		}
+		sums = append(sums, monthSum)
+	}
+	return sums, nil
+}
+
+// CostSummary is the total cost over a period converted into Currency, along with the
+// unconverted subtotals per original currency.
+type CostSummary struct {
+	Currency  string  `json:"currency"`
+	Sum       Money   `json:"sum" swaggertype:"string" example:"1299.00"`
+	Subtotals Amounts `json:"subtotals" swaggertype:"object,string"`
+}
@@ -0,0 +1,238 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func startDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := ParseStartDate("start", s)
+	if err != nil {
+		t.Fatalf("bad date %q: %v", s, err)
+	}
+	return d
+}
+
+func endDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := ParseEndDate("end", s)
+	if err != nil {
+		t.Fatalf("bad date %q: %v", s, err)
+	}
+	return d
+}
+
+func TestSubscriptionModel_CostInPeriod(t *testing.T) {
+	tests := []struct {
+		name       string
+		period     BillingPeriod
+		start      string
+		end        string
+		from, till string
+		wantCharge int
+		// wantCost defaults to wantCharge full charges.
+		wantCost Money
+	}{
+		{name: "open-ended started inside period", start: "03-2025", from: "01-2025", till: "12-2025", wantCharge: 10},
+		{name: "open-ended started before period", start: "06-2024", from: "01-2025", till: "12-2025", wantCharge: 12},
+		{name: "open-ended started after period", start: "02-2026", from: "01-2025", till: "12-2025", wantCharge: 0},
+		{name: "open-ended without lower bound", start: "11-2024", till: "02-2025", wantCharge: 4},
+		{name: "open-ended starting in last month", start: "12-2025", from: "01-2025", till: "12-2025", wantCharge: 1},
+		{name: "closed fully inside period", start: "03-2025", end: "05-2025", from: "01-2025", till: "12-2025", wantCharge: 3},
+		{name: "closed overlapping period start", start: "10-2024", end: "02-2025", from: "01-2025", till: "12-2025", wantCharge: 2},
+		{name: "closed overlapping period end", start: "11-2025", end: "03-2026", from: "01-2025", till: "12-2025", wantCharge: 2},
+		{name: "closed ended before period", start: "01-2024", end: "12-2024", from: "01-2025", till: "12-2025", wantCharge: 0},
+		{name: "single month", start: "05-2025", end: "05-2025", from: "05-2025", till: "05-2025", wantCharge: 1},
+		{name: "yearly charged once in period", period: "P1Y", start: "03-2024", from: "01-2025", till: "12-2025", wantCharge: 1},
+		{name: "yearly renewal outside period", period: "P1Y", start: "03-2024", from: "04-2025", till: "12-2025", wantCharge: 0},
+		{name: "yearly open-ended over years", period: "P1Y", start: "01-2020", till: "12-2025", wantCharge: 6},
+		{name: "quarterly", period: "P3M", start: "01-2025", from: "01-2025", till: "12-2025", wantCharge: 4},
+		// The Apr 1 - Jun 30 cycle is active for 61 of its 91 days.
+		{name: "quarterly ended mid-year", period: "P3M", start: "01-2025", end: "05-2025", from: "01-2025", till: "12-2025", wantCharge: 2, wantCost: 400 + 268},
+		{name: "weekly in one month", period: "P1W", start: "05-2025", from: "05-2025", till: "05-2025", wantCharge: 5},
+		{name: "weekly started earlier", period: "P1W", start: "01-2025", from: "02-2025", till: "02-2025", wantCharge: 4},
+		{name: "daily in february", period: "P1D", start: "01-2024", from: "02-2025", till: "02-2025", wantCharge: 28},
+		{name: "renews mid-month", start: "2025-01-17", from: "01-2025", till: "03-2025", wantCharge: 3},
+		{name: "renewal after period end", start: "2025-01-17", from: "2025-02-01", till: "2025-02-16", wantCharge: 0},
+		{name: "renewal on period end", start: "2025-01-17", from: "2025-02-01", till: "2025-02-17", wantCharge: 1},
+		// The period edges do not prorate: the Jan 17 cycle is billed before the period starts.
+		{name: "period starting mid-cycle", start: "2025-01-17", from: "2025-02-01", till: "2025-03-31", wantCharge: 2},
+		{name: "renewal on end date", start: "2025-01-17", end: "2025-02-17", till: "12-2025", wantCharge: 2, wantCost: 400 + 14}, // 1 of 28 days
+		// The last cycle Mar 17 - Apr 16 is active for 15 of its 31 days.
+		{name: "last cycle prorated", start: "2025-01-17", end: "2025-03-31", till: "12-2025", wantCharge: 3, wantCost: 2*400 + 194},
+		{name: "last cycle complete", start: "2025-01-17", end: "2025-04-16", till: "12-2025", wantCharge: 3},
+		{name: "yearly ended early", period: "P1Y", start: "2025-01-01", end: "2025-03-31", till: "12-2025", wantCharge: 1, wantCost: 99}, // 90 of 365 days
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := &SubscriptionModel{Price: 400, BillingPeriod: tt.period, StartDate: startDate(t, tt.start)}
+			if tt.end != "" {
+				end := endDate(t, tt.end)
+				sub.EndDate = &end
+			}
+
+			var from time.Time
+			if tt.from != "" {
+				from = startDate(t, tt.from)
+			}
+			till := endDate(t, tt.till)
+
+			if got := sub.Charges(from, till); got != tt.wantCharge {
+				t.Errorf("Charges() = %d, want %d", got, tt.wantCharge)
+			}
+			want := tt.wantCost
+			if want == 0 {
+				want = Money(400).Mul(int64(tt.wantCharge))
+			}
+			if got := sub.CostInPeriod(from, till); got != want {
+				t.Errorf("CostInPeriod() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseDateBounds(t *testing.T) {
+	for _, s := range []string{"1899-12-31", "01-2201", "9999-12-31"} {
+		if _, err := ParseStartDate("start_date", s); err == nil {
+			t.Errorf("ParseStartDate(%q) succeeded, want an error", s)
+		}
+	}
+	for _, s := range []string{"1900-01-01", "12-2200"} {
+		if _, err := ParseEndDate("end_date", s); err != nil {
+			t.Errorf("ParseEndDate(%q) error = %v", s, err)
+		}
+	}
+
+	// Three centuries of daily charges, beyond what time.Duration holds.
+	sub := &SubscriptionModel{Price: 1, BillingPeriod: "P1D", StartDate: startDate(t, "1900-01-01")}
+	if got, want := sub.Charges(startDate(t, "2200-12-31"), endDate(t, "2200-12-31")), 1; got != want {
+		t.Errorf("Charges() on the last day = %d, want %d", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := TotalCost(ctx, []*SubscriptionModel{sub}, time.Time{}, endDate(t, "2200-12-31")); err != context.Canceled {
+		t.Errorf("TotalCost() with a cancelled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestBillingPeriod(t *testing.T) {
+	tests := []struct {
+		period  BillingPeriod
+		start   time.Time
+		k       int
+		want    time.Time
+		wantErr bool
+	}{
+		{period: "P1M", start: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), k: 1, want: time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{period: "P1M", start: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), k: 2, want: time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{period: "P1Y", start: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), k: 1, want: time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{period: "P2W", start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), k: 3, want: time.Date(2025, 2, 12, 0, 0, 0, 0, time.UTC)},
+		{period: "P14D", start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), k: 3, want: time.Date(2025, 2, 12, 0, 0, 0, 0, time.UTC)},
+		{period: "P0M", wantErr: true},
+		{period: "P1H", wantErr: true},
+		{period: "1M", wantErr: true},
+		{period: "P+1M", wantErr: true},
+		{period: "P1Y2M", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.period), func(t *testing.T) {
+			if err := tt.period.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tt.period.BillingDate(tt.start, tt.k); !got.Equal(tt.want) {
+				t.Errorf("BillingDate(%d) = %v, want %v", tt.k, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscriptionModel_ChangePrice(t *testing.T) {
+	sub := &SubscriptionModel{Price: 400, StartDate: startDate(t, "01-2025")}
+	sub.ChangePrice(500, startDate(t, "07-2025"))
+
+	if got, want := sub.CostInPeriod(startDate(t, "01-2025"), endDate(t, "12-2025")), Money(6*400+6*500); got != want {
+		t.Errorf("CostInPeriod() after a mid-year change = %v, want %v", got, want)
+	}
+	if got := sub.PriceAt(startDate(t, "2025-06-30")); got != 400 {
+		t.Errorf("PriceAt() before the change = %v, want 400", got)
+	}
+
+	sub.ChangePrice(450, startDate(t, "04-2025"))
+	if len(sub.Prices) != 2 || sub.Prices[1].Price != 450 || sub.Price != 450 {
+		t.Errorf("ChangePrice() before a scheduled change = %+v, want it replaced", sub.Prices)
+	}
+	if got, want := sub.CostInPeriod(startDate(t, "01-2025"), endDate(t, "12-2025")), Money(3*400+9*450); got != want {
+		t.Errorf("CostInPeriod() after the second change = %v, want %v", got, want)
+	}
+
+	sub.ChangePrice(300, time.Time{})
+	if sub.Prices != nil || sub.PriceAt(startDate(t, "01-2025")) != 300 {
+		t.Errorf("ChangePrice() without a day = %+v, want the history replaced", sub.Prices)
+	}
+
+	// A change scheduled for later does not show as the price yet.
+	sub.ChangePrice(600, Today().AddDate(0, 1, 0))
+	if sub.Price != 300 || sub.ToResponse().Price != 300 || sub.PriceAt(Today().AddDate(0, 1, 0)) != 600 {
+		t.Errorf("ChangePrice() next month = price %v, want 300 until then", sub.Price)
+	}
+}
+
+func TestSubscriptionModel_TrialAndIntro(t *testing.T) {
+	trialEnd, introUntil, introPrice := endDate(t, "01-2025"), endDate(t, "03-2025"), Money(100)
+	sub := &SubscriptionModel{Price: 400, StartDate: startDate(t, "01-2025"), TrialEnd: &trialEnd}
+
+	if got, want := sub.CostInPeriod(startDate(t, "01-2025"), endDate(t, "06-2025")), Money(5*400); got != want {
+		t.Errorf("CostInPeriod() with a trial = %v, want %v", got, want)
+	}
+
+	sub.IntroPrice, sub.IntroUntil = &introPrice, &introUntil
+	if got, want := sub.CostInPeriod(startDate(t, "01-2025"), endDate(t, "06-2025")), Money(2*100+3*400); got != want {
+		t.Errorf("CostInPeriod() with a trial and an intro price = %v, want %v", got, want)
+	}
+
+	if !sub.Trialing(startDate(t, "2025-01-31")) || sub.Trialing(startDate(t, "2025-02-01")) {
+		t.Errorf("Trialing() does not end on trial_end")
+	}
+	if err := sub.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	sub.IntroUntil = &trialEnd
+	if err := sub.Validate(); err == nil {
+		t.Errorf("Validate() with intro_until on trial_end error = nil, want an error")
+	}
+}
+
+func TestSubscriptionModel_Pauses(t *testing.T) {
+	end := endDate(t, "12-2025")
+	sub := &SubscriptionModel{Price: 400, StartDate: startDate(t, "01-2025"), EndDate: &end}
+
+	if err := sub.Pause(startDate(t, "04-2025"), nil); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if got := sub.Status(startDate(t, "2025-06-15")); got != StatusPaused {
+		t.Errorf("Status() during the pause = %s, want %s", got, StatusPaused)
+	}
+	if err := sub.Pause(startDate(t, "09-2025"), nil); err == nil {
+		t.Errorf("Pause() inside an open pause error = nil, want a conflict")
+	}
+	if err := sub.Resume(startDate(t, "07-2025")); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if got, want := sub.CostInPeriod(startDate(t, "01-2025"), end), Money(9*400); got != want {
+		t.Errorf("CostInPeriod() with three paused months = %v, want %v", got, want)
+	}
+
+	for day, want := range map[string]string{"2024-12-31": StatusScheduled, "2025-07-01": StatusActive, "2026-01-01": StatusEnded} {
+		if got := sub.Status(startDate(t, day)); got != want {
+			t.Errorf("Status(%s) = %s, want %s", day, got, want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+)
+
+// DefaultCurrency is used for subscriptions created without an explicit currency.
+const DefaultCurrency = "RUB"
+
+// ValidateCurrency checks that code is an ISO 4217 currency code.
+func ValidateCurrency(code string) error {
+	if err := validate.Var(code, "iso4217"); err != nil {
+		return merrors.NewValidationError(fmt.Sprintf("invalid currency %q (expected an ISO 4217 code)", code))
+	}
+	return nil
+}
+
+// ConvertFunc converts an amount from one currency into another.
+type ConvertFunc func(amount Money, from, to string) (Money, error)
+
+// Amounts holds exact amounts per ISO 4217 currency code.
+type Amounts map[string]Money
+
+func (a Amounts) Add(currency string, amount Money) {
+	a[currency] += amount
+}
+
+// Convert returns the sum of all amounts expressed in currency.
+func (a Amounts) Convert(currency string, convert ConvertFunc) (Money, error) {
+	var total Money
+	for from, amount := range a {
+		converted, err := convert(amount, from, currency)
+		if err != nil {
+			return 0, err
+		}
+		total += converted
+	}
+	return total, nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+)
+
+// DateFormat is the day-precision format used in responses and accepted in requests.
+const DateFormat = "2006-01-02"
+
+// MinYear and MaxYear bound the years of the dates accepted in requests, which keeps the periods
+// that aggregates charge cycle by cycle short enough.
+const (
+	MinYear = 1900
+	MaxYear = 2200
+)
+
+// Today returns the current UTC date.
+func Today() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// MonthEnd returns the last day of t's month.
+func MonthEnd(t time.Time) time.Time {
+	return MonthStart(t).AddDate(0, 1, -1)
+}
+
+// ParseStartDate parses a YYYY-MM-DD date, or a MM-YYYY month meaning its first day.
+func ParseStartDate(field, s string) (time.Time, error) {
+	return parseDate(field, s, false)
+}
+
+// ParseEndDate parses a YYYY-MM-DD date, or a MM-YYYY month meaning its last day.
+// End dates are inclusive.
+func ParseEndDate(field, s string) (time.Time, error) {
+	return parseDate(field, s, true)
+}
+
+func parseDate(field, s string, monthEnd bool) (time.Time, error) {
+	d, err := time.Parse(DateFormat, s)
+	if err != nil {
+		m, err := time.Parse(TimeFormat, s)
+		if err != nil {
+			return time.Time{}, merrors.NewValidationError(fmt.Sprintf("invalid %s format (expected YYYY-MM-DD or MM-YYYY)", field))
+		}
+		d = m
+		if monthEnd {
+			d = MonthEnd(m)
+		}
+	}
+
+	if d.Year() < MinYear || d.Year() > MaxYear {
+		return time.Time{}, merrors.NewValidationError(fmt.Sprintf("%s must be between %d and %d", field, MinYear, MaxYear))
+	}
+	return d, nil
+}
+
+// daysBetween returns the number of whole days from a to b. Unlike b.Sub(a), it does not saturate
+// for dates centuries apart.
+func daysBetween(a, b time.Time) int64 {
+	return (b.Unix() - a.Unix()) / (24 * 60 * 60)
+}
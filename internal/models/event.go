@@ -0,0 +1,64 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Actions recorded in the subscription audit log.
+const (
+	ActionCreated  = "created"
+	ActionUpdated  = "updated"
+	ActionDeleted  = "deleted"
+	ActionRestored = "restored"
+	ActionPurged   = "purged"
+)
+
+// SystemActor is recorded for changes made outside of an HTTP request.
+const SystemActor = "system"
+
+// SubscriptionEvent is an entry of a subscription's audit log: a change along with the state of
+// the subscription before and after it. Before is empty for creations, After for deletions, and
+// both for purges.
+type SubscriptionEvent struct {
+	ID             int64             `json:"id"`
+	SubscriptionID int64             `json:"subscription_id"`
+	Action         string            `json:"action" example:"updated"`
+	Actor          string            `json:"actor" example:"support@example.com"`
+	CreatedAt      time.Time         `json:"created_at"`
+	Before         *SubscriptionResp `json:"before,omitempty"`
+	After          *SubscriptionResp `json:"after,omitempty"`
+}
+
+// NewSubscriptionEvent returns an event for a change of the subscription made by the actor of ctx.
+// Either snapshot may be nil.
+func NewSubscriptionEvent(ctx context.Context, action string, before, after *SubscriptionModel) *SubscriptionEvent {
+	event := &SubscriptionEvent{
+		Action: action,
+		Actor:  ActorFromContext(ctx),
+	}
+	if before != nil {
+		event.SubscriptionID = before.ID
+		event.Before = before.ToResponse()
+	}
+	if after != nil {
+		event.SubscriptionID = after.ID
+		event.After = after.ToResponse()
+	}
+	return event
+}
+
+type actorKey struct{}
+
+// ContextWithActor returns a copy of ctx carrying who is making changes, for the audit log.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by ContextWithActor, or SystemActor.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+)
+
+// Formats of ListSubscriptions, chosen with the format query parameter or the Accept header.
+// JSON is a page of subscriptions; the other formats export every matching subscription.
+const (
+	FormatJSON   = "json"
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// ExportContentTypes maps the export formats to their media types.
+var ExportContentTypes = map[string]string{
+	FormatCSV:    "text/csv",
+	FormatNDJSON: "application/x-ndjson",
+}
+
+// ValidateFormat checks that format is FormatJSON or an export format.
+func ValidateFormat(format string) error {
+	if _, ok := ExportContentTypes[format]; !ok && format != FormatJSON {
+		return merrors.NewValidationError(fmt.Sprintf("invalid format %q (expected %s, %s or %s)", format, FormatJSON, FormatCSV, FormatNDJSON))
+	}
+	return nil
+}
+
+// SubscriptionEncoder writes subscriptions one at a time in an export format.
+type SubscriptionEncoder interface {
+	Write(s *SubscriptionModel) error
+	// Flush writes any buffered subscriptions to the underlying writer.
+	Flush() error
+}
+
+// NewSubscriptionEncoder returns an encoder for the export format writing to w.
+func NewSubscriptionEncoder(format string, w io.Writer) (SubscriptionEncoder, error) {
+	switch format {
+	case FormatCSV:
+		return NewSubscriptionCSVWriter(w)
+	case FormatNDJSON:
+		return &ndjsonEncoder{json.NewEncoder(w)}, nil
+	default:
+		return nil, merrors.NewValidationError(fmt.Sprintf("unsupported export format %q", format))
+	}
+}
+
+// ndjsonEncoder writes every subscription as a SubscriptionResp on a line of its own.
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) Write(s *SubscriptionModel) error {
+	return e.enc.Encode(s.ToResponse())
+}
+
+func (e *ndjsonEncoder) Flush() error {
+	return nil
+}
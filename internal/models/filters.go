@@ -1,7 +1,9 @@
 package models
 
 import (
+	"fmt"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/Masterminds/squirrel"
@@ -9,15 +11,44 @@ import (
 	"github.com/google/uuid"
 )
 
+// filterStatuses are the statuses a SubscriptionFilter can select.
+var filterStatuses = map[string]bool{
+	StatusActive:    true,
+	StatusPaused:    true,
+	StatusEnded:     true,
+	StatusScheduled: true,
+	StatusTrialing:  true,
+}
+
 type SubscriptionFilter struct {
-	UserID      uuid.UUID
+	UserID    uuid.UUID
+	ServiceID int64
+	// ServiceName is resolved into ServiceID through the catalog by the service layer; an
+	// unknown name is matched against the stored canonical names as is.
 	ServiceName string
 	From        time.Time
 	Till        time.Time
+
+	// IncludeDeleted makes soft-deleted subscriptions match too.
+	IncludeDeleted bool
+	// Status keeps only the subscriptions in that status today, see SubscriptionModel.Status.
+	// StatusTrialing keeps those whose trial runs today.
+	Status string
+
+	// Currency is the currency aggregates are converted into; it does not filter subscriptions.
+	Currency string
+
+	Limit  uint64
+	Cursor *Cursor
+	Sort   string
+	Desc   bool
 }
 
 func NewSubscriptionFilterFromURL(q url.Values) (*SubscriptionFilter, error) {
-	filter := &SubscriptionFilter{}
+	filter := &SubscriptionFilter{
+		Limit: DefaultPageLimit,
+		Sort:  SortByID,
+	}
 
 	if userIDStr := q.Get("user_id"); userIDStr != "" {
 		userID, err := uuid.Parse(userIDStr)
@@ -27,26 +58,87 @@ func NewSubscriptionFilterFromURL(q url.Values) (*SubscriptionFilter, error) {
 		filter.UserID = userID
 	}
 
+	if serviceIDStr := q.Get("service_id"); serviceIDStr != "" {
+		serviceID, err := strconv.ParseInt(serviceIDStr, 10, 64)
+		if err != nil || serviceID <= 0 {
+			return nil, merrors.NewValidationError("Invalid service_id")
+		}
+		filter.ServiceID = serviceID
+	}
+
 	if name := q.Get("service_name"); name != "" {
 		filter.ServiceName = name
 	}
 
 	if fromStr := q.Get("from"); fromStr != "" {
-		from, err := time.Parse(TimeFormat, fromStr)
+		from, err := ParseStartDate("from", fromStr)
 		if err != nil {
-			return nil, merrors.NewValidationError("Invalid from date")
+			return nil, err
 		}
 		filter.From = from
 	}
 
 	if toStr := q.Get("till"); toStr != "" {
-		to, err := time.Parse(TimeFormat, toStr)
+		to, err := ParseEndDate("till", toStr)
 		if err != nil {
-			return nil, merrors.NewValidationError("Invalid till_date")
+			return nil, err
 		}
 		filter.Till = to
 	}
 
+	if includeDeleted := q.Get("include_deleted"); includeDeleted != "" {
+		include, err := strconv.ParseBool(includeDeleted)
+		if err != nil {
+			return nil, merrors.NewValidationError("include_deleted must be true or false")
+		}
+		filter.IncludeDeleted = include
+	}
+
+	if status := q.Get("status"); status != "" {
+		if !filterStatuses[status] {
+			return nil, merrors.NewValidationError("status must be one of active, paused, ended, scheduled, trialing")
+		}
+		filter.Status = status
+	}
+
+	if currency := q.Get("currency"); currency != "" {
+		if err := ValidateCurrency(currency); err != nil {
+			return nil, err
+		}
+		filter.Currency = currency
+	}
+
+	if limitStr := q.Get("limit"); limitStr != "" {
+		limit, err := strconv.ParseUint(limitStr, 10, 64)
+		if err != nil || limit == 0 || limit > MaxPageLimit {
+			return nil, merrors.NewValidationError(fmt.Sprintf("limit must be between 1 and %d", MaxPageLimit))
+		}
+		filter.Limit = limit
+	}
+
+	if sort := q.Get("sort"); sort != "" {
+		if !sortFields[sort] {
+			return nil, merrors.NewValidationError("sort must be one of id, price, start_date, service_name")
+		}
+		filter.Sort = sort
+	}
+
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return nil, merrors.NewValidationError("order must be asc or desc")
+	}
+
+	if cursorStr := q.Get("cursor"); cursorStr != "" {
+		cursor, err := ParseCursor(cursorStr)
+		if err != nil {
+			return nil, err
+		}
+		filter.Cursor = cursor
+	}
+
 	return filter, nil
 }
 
@@ -54,25 +146,111 @@ func (f *SubscriptionFilter) Validate() error {
 	if !f.From.IsZero() && !f.Till.IsZero() && f.From.After(f.Till) {
 		return merrors.NewValidationError("from_date must be before or equal to till_date")
 	}
+	if f.Cursor != nil && (f.Cursor.Sort != f.Sort || f.Cursor.Desc != f.Desc) {
+		return merrors.NewValidationError("cursor does not match the requested sort")
+	}
 	return nil
 }
 
 func (f *SubscriptionFilter) ToSQL(builder squirrel.SelectBuilder) squirrel.SelectBuilder {
+	if !f.IncludeDeleted {
+		builder = builder.Where(squirrel.Eq{"deleted_at": nil})
+	}
+
 	if f.UserID != uuid.Nil {
 		builder = builder.Where(squirrel.Eq{"user_id": f.UserID})
 	}
 
+	if f.ServiceID != 0 {
+		builder = builder.Where(squirrel.Eq{"service_id": f.ServiceID})
+	}
+
 	if f.ServiceName != "" {
 		builder = builder.Where(squirrel.Eq{"service_name": f.ServiceName})
 	}
 
+	// A subscription matches the period if its [start_date, end_date] range overlaps [from, till].
 	if !f.From.IsZero() {
-		builder = builder.Where(squirrel.GtOrEq{"start_date": f.From})
+		builder = builder.Where(squirrel.Or{
+			squirrel.Eq{"end_date": nil},
+			squirrel.GtOrEq{"end_date": f.From},
+		})
 	}
 
 	if !f.Till.IsZero() {
 		builder = builder.Where(squirrel.LtOrEq{"start_date": f.Till})
 	}
 
+	if f.Status != "" {
+		builder = builder.Where(statusToSQL(f.Status, Today()))
+	}
+
 	return builder
 }
+
+// Matches reports whether the subscription satisfies the filter, with the same semantics as ToSQL.
+func (f *SubscriptionFilter) Matches(s *SubscriptionModel) bool {
+	if !f.IncludeDeleted && s.DeletedAt != nil {
+		return false
+	}
+
+	if f.UserID != uuid.Nil && s.UserID != f.UserID {
+		return false
+	}
+
+	if f.ServiceID != 0 && s.ServiceID != f.ServiceID {
+		return false
+	}
+
+	if f.ServiceName != "" && s.ServiceName != f.ServiceName {
+		return false
+	}
+
+	if !f.From.IsZero() && s.EndDate != nil && s.EndDate.Before(f.From) {
+		return false
+	}
+
+	if !f.Till.IsZero() && s.StartDate.After(f.Till) {
+		return false
+	}
+
+	if f.Status != "" && !s.hasStatus(f.Status, Today()) {
+		return false
+	}
+
+	return true
+}
+
+// hasStatus reports whether the subscription is in the status on day, where StatusTrialing
+// means that its trial runs.
+func (s *SubscriptionModel) hasStatus(status string, day time.Time) bool {
+	if status == StatusTrialing {
+		return s.Trialing(day)
+	}
+	return s.Status(day) == status
+}
+
+// statusToSQL selects the subscriptions in the status on today, with the semantics of
+// SubscriptionModel.Status and Trialing.
+func statusToSQL(status string, today time.Time) squirrel.Sqlizer {
+	live := squirrel.And{
+		squirrel.LtOrEq{"start_date": today},
+		squirrel.Or{squirrel.Eq{"end_date": nil}, squirrel.GtOrEq{"end_date": today}},
+	}
+	paused := `EXISTS (
+        SELECT 1 FROM subscription_pauses p
+        WHERE p.subscription_id = subscriptions.id AND p.paused_from <= ? AND (p.paused_until IS NULL OR p.paused_until >= ?))`
+
+	switch status {
+	case StatusScheduled:
+		return squirrel.Gt{"start_date": today}
+	case StatusEnded:
+		return squirrel.Lt{"end_date": today}
+	case StatusPaused:
+		return append(live, squirrel.Expr(paused, today, today))
+	case StatusTrialing:
+		return squirrel.And{squirrel.LtOrEq{"start_date": today}, squirrel.GtOrEq{"trial_end": today}}
+	default:
+		return append(live, squirrel.Expr("NOT "+paused, today, today))
+	}
+}
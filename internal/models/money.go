@@ -0,0 +1,136 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+)
+
+// Money is an exact amount in minor currency units (kopecks, cents).
+// On the wire it is a decimal string with two fraction digits, e.g. "399.99";
+// on input both strings and plain JSON numbers are accepted and parsed without floats.
+type Money int64
+
+const (
+	moneyScale     = 100
+	moneyFracDigit = 2
+)
+
+// ParseMoney parses a decimal amount such as "400", "399.9" or "399.99".
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || len(frac) > moneyFracDigit || !isDigits(whole) || !isDigits(frac) {
+		return 0, merrors.NewValidationError(fmt.Sprintf("invalid amount %q (expected a decimal with at most %d fraction digits)", s, moneyFracDigit))
+	}
+	frac += strings.Repeat("0", moneyFracDigit-len(frac))
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > math.MaxInt64/moneyScale-1 {
+		return 0, merrors.NewValidationError(fmt.Sprintf("amount %q is out of range", s))
+	}
+	cents, _ := strconv.ParseInt(frac, 10, 64)
+
+	m := Money(units*moneyScale + cents)
+	if neg {
+		m = -m
+	}
+	return m, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/moneyScale, v%moneyScale)
+}
+
+// Mul returns the amount multiplied by n.
+func (m Money) Mul(n int64) Money {
+	return m * Money(n)
+}
+
+// Prorate returns the amount scaled by num/den, rounded half away from zero.
+func (m Money) Prorate(num, den int64) Money {
+	return MoneyFromRat(new(big.Rat).Mul(m.Rat(), big.NewRat(num, den)))
+}
+
+// Rat returns the amount in minor units as a big.Rat, for exact arithmetic.
+func (m Money) Rat() *big.Rat {
+	return new(big.Rat).SetInt64(int64(m))
+}
+
+// MoneyFromRat rounds an amount in minor units half away from zero.
+func MoneyFromRat(r *big.Rat) Money {
+	num, den := r.Num(), r.Denom()
+
+	twice := new(big.Int).Lsh(num, 1)
+	if num.Sign() >= 0 {
+		twice.Add(twice, den)
+	} else {
+		twice.Sub(twice, den)
+	}
+	return Money(twice.Quo(twice, new(big.Int).Lsh(den, 1)).Int64())
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	s := string(data)
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		*m = Money(v)
+	case nil:
+		*m = 0
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
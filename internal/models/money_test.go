@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoney_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: `400`, want: 400_00},
+		{in: `"400"`, want: 400_00},
+		{in: `399.99`, want: 399_99},
+		{in: `"399.9"`, want: 399_90},
+		{in: `0.1`, want: 10},
+		{in: `"0.07"`, want: 7},
+		{in: `"-1.50"`, want: -1_50},
+		{in: `399.999`, wantErr: true},
+		{in: `1e3`, wantErr: true},
+		{in: `"12,50"`, wantErr: true},
+		{in: `".50"`, wantErr: true},
+		{in: `""`, wantErr: true},
+		{in: `"99999999999999999999"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var got Money
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{in: 0, want: "0.00"},
+		{in: 7, want: "0.07"},
+		{in: 399_99, want: "399.99"},
+		{in: -1_50, want: "-1.50"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
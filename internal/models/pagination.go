@@ -0,0 +1,236 @@
+package models
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 1000
+)
+
+// Sort fields accepted by the sort query parameter. SortByPrice compares amounts as they are,
+// regardless of their currency.
+const (
+	SortByID          = "id"
+	SortByPrice       = "price"
+	SortByStartDate   = "start_date"
+	SortByServiceName = "service_name"
+)
+
+var sortFields = map[string]bool{
+	SortByID:          true,
+	SortByPrice:       true,
+	SortByStartDate:   true,
+	SortByServiceName: true,
+}
+
+// Cursor points at the last row of a page. It is handed to clients as an opaque
+// base64 string and is only valid for the sort it was issued with.
+type Cursor struct {
+	Sort  string          `json:"s"`
+	Desc  bool            `json:"d"`
+	Value json.RawMessage `json:"v,omitempty"`
+	ID    int64           `json:"id"`
+}
+
+func NewCursor(sort string, desc bool, last *SubscriptionModel) (*Cursor, error) {
+	cursor := &Cursor{Sort: sort, Desc: desc, ID: last.ID}
+	if sort != SortByID {
+		value, err := json.Marshal(last.SortValue(sort))
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal cursor value: %w", err)
+		}
+		cursor.Value = value
+	}
+	return cursor, nil
+}
+
+func ParseCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, merrors.NewValidationError("Invalid cursor")
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || !sortFields[cursor.Sort] {
+		return nil, merrors.NewValidationError("Invalid cursor")
+	}
+	return &cursor, nil
+}
+
+func (c *Cursor) String() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// SortValue decodes the cursor value into the Go type of its sort column.
+func (c *Cursor) SortValue() (any, error) {
+	var err error
+	switch c.Sort {
+	case SortByPrice:
+		var v Money
+		err = json.Unmarshal(c.Value, &v)
+		return v, err
+	case SortByStartDate:
+		var v time.Time
+		err = json.Unmarshal(c.Value, &v)
+		return v, err
+	case SortByServiceName:
+		var v string
+		err = json.Unmarshal(c.Value, &v)
+		return v, err
+	default:
+		return c.ID, nil
+	}
+}
+
+// SortValue returns the value of the subscription's sort column.
+func (s *SubscriptionModel) SortValue(sort string) any {
+	switch sort {
+	case SortByPrice:
+		return s.PriceAt(Today())
+	case SortByStartDate:
+		return s.StartDate
+	case SortByServiceName:
+		return s.ServiceName
+	default:
+		return s.ID
+	}
+}
+
+// Compare orders a and b by the filter sort, with id as a tie-breaker, the same way as PageToSQL.
+func (f *SubscriptionFilter) Compare(a, b *SubscriptionModel) int {
+	c := compareSortValues(a.SortValue(f.Sort), b.SortValue(f.Sort))
+	if c == 0 {
+		c = cmp.Compare(a.ID, b.ID)
+	}
+	if f.Desc {
+		return -c
+	}
+	return c
+}
+
+// AfterCursor reports whether the subscription comes after the filter cursor in sort order.
+func (f *SubscriptionFilter) AfterCursor(s *SubscriptionModel) bool {
+	if f.Cursor == nil {
+		return true
+	}
+
+	value, err := f.Cursor.SortValue()
+	if err != nil {
+		return false
+	}
+
+	c := compareSortValues(s.SortValue(f.Sort), value)
+	if c == 0 || f.Sort == SortByID {
+		c = cmp.Compare(s.ID, f.Cursor.ID)
+	}
+	if f.Desc {
+		return c < 0
+	}
+	return c > 0
+}
+
+func compareSortValues(a, b any) int {
+	switch a := a.(type) {
+	case Money:
+		return cmp.Compare(a, b.(Money))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return cmp.Compare(a, b.(string))
+	case int64:
+		return cmp.Compare(a, b.(int64))
+	}
+	return 0
+}
+
+// SubscriptionPage is a single page of ListSubscriptions results.
+type SubscriptionPage struct {
+	Subscriptions []*SubscriptionModel `json:"subscriptions"`
+	NextCursor    string               `json:"next_cursor,omitempty"`
+	TotalCount    int64                `json:"total_count"`
+}
+
+// currentPriceSQL is the price in force today of a subscriptions row, like PriceAt(Today()). The
+// price column holds it as of the last change, which a scheduled price may have overtaken since.
+const currentPriceSQL = `COALESCE((
+        SELECT p.price FROM subscription_prices p
+        WHERE p.subscription_id = subscriptions.id AND p.effective_from <= (now() AT TIME ZONE 'UTC')::date
+        ORDER BY p.effective_from DESC
+        LIMIT 1), price)`
+
+// sortToSQL returns the SQL expression of a sort column.
+func sortToSQL(sort string) string {
+	if sort == SortByPrice {
+		return currentPriceSQL
+	}
+	return sort
+}
+
+// PageToSQL orders the query by the filter sort, with id as a tie-breaker, and limits it
+// to the rows after the cursor. One extra row is requested to detect whether a next page exists.
+func (f *SubscriptionFilter) PageToSQL(builder squirrel.SelectBuilder) (squirrel.SelectBuilder, error) {
+	op := ">"
+	if f.Desc {
+		op = "<"
+	}
+
+	if f.Cursor != nil {
+		if f.Sort == SortByID {
+			builder = builder.Where("id "+op+" ?", f.Cursor.ID)
+		} else {
+			value, err := f.Cursor.SortValue()
+			if err != nil {
+				return builder, merrors.NewValidationError("Invalid cursor")
+			}
+			builder = builder.Where(fmt.Sprintf("(%s, id) %s (?, ?)", sortToSQL(f.Sort), op), value, f.Cursor.ID)
+		}
+	}
+
+	return f.OrderToSQL(builder).Limit(f.Limit + 1), nil
+}
+
+// OrderToSQL orders the query by the filter sort, with id as a tie-breaker.
+func (f *SubscriptionFilter) OrderToSQL(builder squirrel.SelectBuilder) squirrel.SelectBuilder {
+	dir := "ASC"
+	if f.Desc {
+		dir = "DESC"
+	}
+
+	if f.Sort != SortByID {
+		builder = builder.OrderBy(sortToSQL(f.Sort) + " " + dir)
+	}
+	return builder.OrderBy("id " + dir)
+}
+
+// NewSubscriptionPage builds a page from the rows fetched with PageToSQL, which may hold
+// one extra row beyond the filter limit.
+func NewSubscriptionPage(f *SubscriptionFilter, rows []*SubscriptionModel, total int64) (*SubscriptionPage, error) {
+	page := &SubscriptionPage{
+		Subscriptions: rows,
+		TotalCount:    total,
+	}
+	if page.Subscriptions == nil {
+		page.Subscriptions = []*SubscriptionModel{}
+	}
+
+	if uint64(len(rows)) > f.Limit {
+		page.Subscriptions = rows[:f.Limit]
+		cursor, err := NewCursor(f.Sort, f.Desc, page.Subscriptions[f.Limit-1])
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = cursor.String()
+	}
+
+	return page, nil
+}
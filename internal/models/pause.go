@@ -0,0 +1,150 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+)
+
+// Statuses of a subscription on a given day, see Status.
+const (
+	StatusActive    = "active"
+	StatusPaused    = "paused"
+	StatusEnded     = "ended"
+	StatusScheduled = "scheduled"
+)
+
+// SubscriptionPause is a period during which a subscription is paused and its billing dates are
+// not charged. Both days are inclusive; a pause without Until lasts until it is resumed.
+type SubscriptionPause struct {
+	From  time.Time
+	Until *time.Time
+}
+
+// SubscriptionPauseResp is the wire format of a SubscriptionPause.
+type SubscriptionPauseResp struct {
+	From  string  `json:"from" example:"2025-09-01"`
+	Until *string `json:"until,omitempty" example:"2025-11-30"`
+}
+
+func (p SubscriptionPause) ToResponse() SubscriptionPauseResp {
+	resp := SubscriptionPauseResp{From: p.From.Format(DateFormat)}
+	if p.Until != nil {
+		until := p.Until.Format(DateFormat)
+		resp.Until = &until
+	}
+	return resp
+}
+
+// Covers reports whether day falls inside the pause.
+func (p SubscriptionPause) Covers(day time.Time) bool {
+	return !day.Before(p.From) && (p.Until == nil || !day.After(*p.Until))
+}
+
+// Paused reports whether the subscription is paused on day.
+func (s *SubscriptionModel) Paused(day time.Time) bool {
+	return slices.ContainsFunc(s.Pauses, func(p SubscriptionPause) bool { return p.Covers(day) })
+}
+
+// Status returns the status of the subscription on day: scheduled before it starts, ended after
+// its end date, and paused or active in between.
+func (s *SubscriptionModel) Status(day time.Time) string {
+	switch {
+	case s.StartDate.After(day):
+		return StatusScheduled
+	case s.EndDate != nil && s.EndDate.Before(day):
+		return StatusEnded
+	case s.Paused(day):
+		return StatusPaused
+	default:
+		return StatusActive
+	}
+}
+
+// Pause pauses the subscription from from through until, or until it is resumed when until is
+// nil. It fails with a ConflictError if the subscription is already paused during that time.
+func (s *SubscriptionModel) Pause(from time.Time, until *time.Time) error {
+	if from.Before(s.StartDate) {
+		return merrors.NewValidationError("a pause cannot start before start_date")
+	}
+	if s.EndDate != nil && from.After(*s.EndDate) {
+		return merrors.NewValidationError("a pause cannot start after end_date")
+	}
+	if until != nil && until.Before(from) {
+		return merrors.NewValidationError("a pause must end after it starts")
+	}
+
+	for _, p := range s.Pauses {
+		if (until == nil || !p.From.After(*until)) && (p.Until == nil || !from.After(*p.Until)) {
+			return merrors.NewConflictError(fmt.Sprintf("subscription is already paused from %s", p.From.Format(DateFormat)))
+		}
+	}
+
+	s.Pauses = append(s.Pauses, SubscriptionPause{From: from, Until: until})
+	slices.SortFunc(s.Pauses, func(a, b SubscriptionPause) int { return a.From.Compare(b.From) })
+	return nil
+}
+
+// Resume makes the subscription active again from day on: the pause covering day ends the day
+// before, and an open pause that has not started by then is cancelled. It fails with a
+// ConflictError if there is no such pause.
+func (s *SubscriptionModel) Resume(day time.Time) error {
+	i := slices.IndexFunc(s.Pauses, func(p SubscriptionPause) bool { return p.Covers(day) })
+	if i < 0 {
+		i = slices.IndexFunc(s.Pauses, func(p SubscriptionPause) bool { return p.Until == nil })
+	}
+	if i < 0 {
+		return merrors.NewConflictError(fmt.Sprintf("subscription is not paused on %s", day.Format(DateFormat)))
+	}
+
+	if !s.Pauses[i].From.Before(day) {
+		s.Pauses = slices.Delete(s.Pauses, i, i+1)
+		return nil
+	}
+	until := day.AddDate(0, 0, -1)
+	s.Pauses[i].Until = &until
+	return nil
+}
+
+// SubscriptionPauseReq pauses a subscription. From defaults to today; without Until the
+// subscription stays paused until it is resumed. Both are in the format of the start and end
+// dates of a subscription respectively.
+type SubscriptionPauseReq struct {
+	From  *string `json:"from" example:"2025-09-01"`
+	Until *string `json:"until" example:"11-2025"`
+}
+
+// Apply pauses the subscription as requested, with today as the default start.
+func (r *SubscriptionPauseReq) Apply(s *SubscriptionModel, today time.Time) error {
+	from := today
+	if r.From != nil {
+		var err error
+		if from, err = ParseStartDate("from", *r.From); err != nil {
+			return err
+		}
+	}
+	until, err := parseOptionalEndDate("until", r.Until)
+	if err != nil {
+		return err
+	}
+	return s.Pause(from, until)
+}
+
+// SubscriptionResumeReq resumes a paused subscription from Date on, today by default.
+type SubscriptionResumeReq struct {
+	Date *string `json:"date" example:"2025-10-01"`
+}
+
+// Apply resumes the subscription as requested, with today as the default date.
+func (r *SubscriptionResumeReq) Apply(s *SubscriptionModel, today time.Time) error {
+	day := today
+	if r.Date != nil {
+		var err error
+		if day, err = ParseStartDate("date", *r.Date); err != nil {
+			return err
+		}
+	}
+	return s.Resume(day)
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// SubscriptionPrice is a price of a subscription and the day it takes effect on, in the
+// subscription's currency.
+type SubscriptionPrice struct {
+	EffectiveFrom time.Time
+	Price         Money
+}
+
+// SubscriptionPriceResp is the wire format of a SubscriptionPrice.
+type SubscriptionPriceResp struct {
+	EffectiveFrom string `json:"effective_from" example:"2025-07-01"`
+	Price         Money  `json:"price" swaggertype:"string" example:"399.99"`
+}
+
+// PriceHistory returns the prices of the subscription, oldest first. A subscription whose price
+// never changed has had Price since StartDate.
+func (s *SubscriptionModel) PriceHistory() []SubscriptionPrice {
+	if len(s.Prices) == 0 {
+		return []SubscriptionPrice{{EffectiveFrom: s.StartDate, Price: s.Price}}
+	}
+	return s.Prices
+}
+
+// PriceAt returns the price in force on day: the latest one effective on or before it, or the
+// first one for days before any took effect.
+func (s *SubscriptionModel) PriceAt(day time.Time) Money {
+	history := s.PriceHistory()
+	price := history[0].Price
+	for _, p := range history[1:] {
+		if p.EffectiveFrom.After(day) {
+			break
+		}
+		price = p.Price
+	}
+	return price
+}
+
+// ChangePrice makes price the price of the subscription from day on, replacing the changes that
+// were to take effect on or after it. Price is left as the price in force today. A zero day, or
+// one not after StartDate, replaces the whole history, as if the subscription always had that
+// price.
+func (s *SubscriptionModel) ChangePrice(price Money, day time.Time) {
+	if day.IsZero() || !day.After(s.StartDate) {
+		s.Price, s.Prices = price, nil
+		return
+	}
+
+	history := s.PriceHistory()
+	i := slices.IndexFunc(history, func(p SubscriptionPrice) bool { return !p.EffectiveFrom.Before(day) })
+	if i < 0 {
+		i = len(history)
+	}
+	s.Prices = append(slices.Clip(history[:i]), SubscriptionPrice{EffectiveFrom: day, Price: price})
+	s.Price = s.PriceAt(Today())
+}
+
+func (p SubscriptionPrice) ToResponse() SubscriptionPriceResp {
+	return SubscriptionPriceResp{EffectiveFrom: p.EffectiveFrom.Format(DateFormat), Price: p.Price}
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of reminders.
+const (
+	// ReminderRenewal announces the next charge of a subscription.
+	ReminderRenewal = "renewal"
+	// ReminderEnding announces the last day of a subscription.
+	ReminderEnding = "ending"
+)
+
+// Reminder tells the user of a subscription that it renews or ends soon. A reminder is sent
+// once per kind, subscription and date.
+type Reminder struct {
+	Kind           string    `json:"kind" example:"renewal"`
+	SubscriptionID int64     `json:"subscription_id" example:"42"`
+	UserID         uuid.UUID `json:"user_id"`
+	ServiceName    string    `json:"service_name" example:"Netflix"`
+	// Date is the renewal date, or the last day of an ending subscription, in DateFormat.
+	Date string `json:"date" example:"2025-08-17"`
+	// Amount is what the renewal charges; ending reminders have none.
+	Amount   *Money `json:"amount,omitempty" swaggertype:"string" example:"399.99"`
+	Currency string `json:"currency" example:"RUB"`
+}
+
+// Key identifies the reminder for deduplication.
+func (r *Reminder) Key() string {
+	return fmt.Sprintf("%s/%d/%s", r.Kind, r.SubscriptionID, r.Date)
+}
+
+// Reminders returns the reminders of the subscription due on today: one for its next billing
+// date within days unless that charges nothing (during a pause or a trial), and one for its end
+// date within days.
+func (s *SubscriptionModel) Reminders(today time.Time, days int) []*Reminder {
+	horizon := today.AddDate(0, 0, days)
+	var reminders []*Reminder
+
+	renewal := s.BillingPeriod.BillingDate(s.StartDate, s.BillingPeriod.firstBillingIndex(s.StartDate, today))
+	if !renewal.After(horizon) && (s.EndDate == nil || !renewal.After(*s.EndDate)) {
+		if amount := s.priceOn(renewal); amount > 0 {
+			reminder := s.reminder(ReminderRenewal, renewal)
+			reminder.Amount = &amount
+			reminders = append(reminders, reminder)
+		}
+	}
+
+	if s.EndDate != nil && !s.EndDate.Before(today) && !s.EndDate.After(horizon) {
+		reminders = append(reminders, s.reminder(ReminderEnding, *s.EndDate))
+	}
+
+	return reminders
+}
+
+func (s *SubscriptionModel) reminder(kind string, date time.Time) *Reminder {
+	return &Reminder{
+		Kind:           kind,
+		SubscriptionID: s.ID,
+		UserID:         s.UserID,
+		ServiceName:    s.ServiceName,
+		Date:           date.Format(DateFormat),
+		Currency:       s.Currency,
+	}
+}
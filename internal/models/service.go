@@ -0,0 +1,149 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+)
+
+// ServiceModel is an entry of the service catalog. Subscriptions reference it by ID; its
+// canonical name and aliases are what service names given by clients are resolved against.
+type ServiceModel struct {
+	ID       int64    `json:"id"`
+	Name     string   `json:"name" example:"Yandex Plus"`
+	Aliases  []string `json:"aliases" example:"Яндекс Плюс"`
+	Category string   `json:"category,omitempty" example:"streaming"`
+	// DefaultPrice is used for new subscriptions to the service that do not set a price.
+	DefaultPrice *Money `json:"default_price,omitempty" swaggertype:"string" example:"399.00"`
+	Currency     string `json:"currency" example:"RUB"`
+}
+
+// NormalizeServiceName returns the key service names are compared by: case-insensitive and
+// with runs of whitespace collapsed, so "Yandex Plus" and " yandex  plus" are the same service.
+func NormalizeServiceName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// Names returns the canonical name followed by the aliases.
+func (s *ServiceModel) Names() []string {
+	return append([]string{s.Name}, s.Aliases...)
+}
+
+// Validate checks that the names are set and distinct after normalization.
+func (s *ServiceModel) Validate() error {
+	seen := make(map[string]bool)
+	for _, name := range s.Names() {
+		key := NormalizeServiceName(name)
+		if key == "" {
+			return merrors.NewValidationError("service name and aliases cannot be empty")
+		}
+		if seen[key] {
+			return merrors.NewValidationError(fmt.Sprintf("duplicate service name %q", name))
+		}
+		seen[key] = true
+	}
+
+	if s.DefaultPrice != nil && *s.DefaultPrice <= 0 {
+		return merrors.NewValidationError("default_price must be greater than 0")
+	}
+	return ValidateCurrency(s.Currency)
+}
+
+type ServiceCreateReq struct {
+	Name         string   `json:"name" validate:"required" example:"Yandex Plus"`
+	Aliases      []string `json:"aliases,omitempty" example:"Яндекс Плюс"`
+	Category     string   `json:"category,omitempty" example:"streaming"`
+	DefaultPrice *Money   `json:"default_price,omitempty" swaggertype:"string" example:"399.00"`
+	// Currency of the default price, defaults to RUB.
+	Currency string `json:"currency,omitempty" validate:"omitempty,iso4217" example:"RUB"`
+}
+
+func (s *ServiceCreateReq) Validate() error {
+	if err := validate.Struct(s); err != nil {
+		return merrors.NewValidationError(err.Error())
+	}
+	return s.ToModel().Validate()
+}
+
+func (s *ServiceCreateReq) ToModel() *ServiceModel {
+	service := &ServiceModel{
+		Name:         strings.TrimSpace(s.Name),
+		Aliases:      trimNames(s.Aliases),
+		Category:     strings.TrimSpace(s.Category),
+		DefaultPrice: s.DefaultPrice,
+		Currency:     s.Currency,
+	}
+	if service.Currency == "" {
+		service.Currency = DefaultCurrency
+	}
+	return service
+}
+
+// ServiceUpdateReq patches a catalog entry. Aliases, when set, replace the existing ones.
+type ServiceUpdateReq struct {
+	Name         *string   `json:"name" example:"Yandex Plus"`
+	Aliases      *[]string `json:"aliases" example:"Яндекс Плюс"`
+	Category     *string   `json:"category" example:"streaming"`
+	DefaultPrice *Money    `json:"default_price" swaggertype:"string" example:"399.00"`
+	Currency     *string   `json:"currency" example:"RUB"`
+}
+
+// PatchModel applies the request to the service. The result must be checked with Validate.
+func (s *ServiceUpdateReq) PatchModel(service *ServiceModel) {
+	if s.Name != nil {
+		service.Name = strings.TrimSpace(*s.Name)
+	}
+	if s.Aliases != nil {
+		service.Aliases = trimNames(*s.Aliases)
+	}
+	if s.Category != nil {
+		service.Category = strings.TrimSpace(*s.Category)
+	}
+	if s.DefaultPrice != nil {
+		service.DefaultPrice = s.DefaultPrice
+	}
+	if s.Currency != nil {
+		service.Currency = *s.Currency
+	}
+}
+
+func trimNames(names []string) []string {
+	trimmed := make([]string, 0, len(names))
+	for _, name := range names {
+		trimmed = append(trimmed, strings.TrimSpace(name))
+	}
+	return trimmed
+}
+
+type ServiceFilter struct {
+	// Name matches the canonical name or any alias, after normalization.
+	Name     string
+	Category string
+}
+
+func NewServiceFilterFromURL(q url.Values) *ServiceFilter {
+	return &ServiceFilter{
+		Name:     q.Get("name"),
+		Category: q.Get("category"),
+	}
+}
+
+// Matches reports whether the service satisfies the filter.
+func (f *ServiceFilter) Matches(s *ServiceModel) bool {
+	if f.Category != "" && s.Category != f.Category {
+		return false
+	}
+	if f.Name == "" {
+		return true
+	}
+
+	key := NormalizeServiceName(f.Name)
+	for _, name := range s.Names() {
+		if NormalizeServiceName(name) == key {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
@@ -8,23 +9,130 @@ import (
 	"github.com/google/uuid"
 )
 
+// TimeFormat is the month-precision format accepted in requests for backward compatibility.
 const TimeFormat = "01-2006"
 
+// SubscriptionModel is a stored subscription. It is marshaled to JSON as SubscriptionResp.
 type SubscriptionModel struct {
-	ID          int64
-	ServiceName string
-	UserID      uuid.UUID
-	Price       float64
-	StartDate   time.Time
-	EndDate     *time.Time
+	ID        int64 `json:"id"`
+	ServiceID int64 `json:"service_id"`
+	// ServiceName is the canonical name of the service in the catalog.
+	ServiceName   string        `json:"service_name"`
+	UserID        uuid.UUID     `json:"user_id"`
+	Price         Money         `json:"price" swaggertype:"string" example:"399.99"`
+	Currency      string        `json:"currency" example:"RUB"`
+	BillingPeriod BillingPeriod `json:"billing_period" swaggertype:"string" example:"P1M"`
+	StartDate     time.Time     `json:"start_date" swaggertype:"string" example:"2025-07-17"`
+	EndDate       *time.Time    `json:"end_date,omitempty" swaggertype:"string" example:"2025-12-16"`
+	// DeletedAt is set once the subscription is soft-deleted.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Version is incremented by every change, for optimistic concurrency control.
+	Version int64 `json:"version"`
+	// Prices is the price history, oldest first, when the price changed over time; Price is then
+	// the latest price. See PriceHistory.
+	Prices []SubscriptionPrice `json:"-"`
+	// TrialEnd is the last day of a free trial: billing dates up to it are not charged.
+	TrialEnd *time.Time `json:"-"`
+	// IntroPrice is charged instead of the price on the billing dates after the trial up to
+	// IntroUntil. Both are set or neither is.
+	IntroPrice *Money     `json:"-"`
+	IntroUntil *time.Time `json:"-"`
+	// Pauses are the periods the subscription is paused, oldest first.
+	Pauses []SubscriptionPause `json:"-"`
+}
+
+// SubscriptionResp is the wire format of a subscription. Dates use DateFormat, so a response
+// can be sent back as a SubscriptionUpdateReq as is.
+type SubscriptionResp struct {
+	ID            int64         `json:"id"`
+	ServiceID     int64         `json:"service_id"`
+	ServiceName   string        `json:"service_name"`
+	UserID        uuid.UUID     `json:"user_id"`
+	Price         Money         `json:"price" swaggertype:"string" example:"399.99"`
+	Currency      string        `json:"currency" example:"RUB"`
+	BillingPeriod BillingPeriod `json:"billing_period" swaggertype:"string" example:"P1M"`
+	StartDate     string        `json:"start_date" example:"2025-07-17"`
+	EndDate       *string       `json:"end_date,omitempty" example:"2025-12-16"`
+	DeletedAt     *time.Time    `json:"deleted_at,omitempty"`
+	Version       int64         `json:"version"`
+	// Prices lists the price of the subscription over time, oldest first.
+	Prices     []SubscriptionPriceResp `json:"prices"`
+	TrialEnd   *string                 `json:"trial_end,omitempty" example:"2025-08-16"`
+	IntroPrice *Money                  `json:"intro_price,omitempty" swaggertype:"string" example:"99.00"`
+	IntroUntil *string                 `json:"intro_until,omitempty" example:"2025-10-16"`
+	Pauses     []SubscriptionPauseResp `json:"pauses,omitempty"`
+	// Status is the status of the subscription when the response was made.
+	Status string `json:"status" enums:"active,paused,ended,scheduled" example:"active"`
+}
+
+func (s *SubscriptionModel) ToResponse() *SubscriptionResp {
+	resp := &SubscriptionResp{
+		ID:            s.ID,
+		ServiceID:     s.ServiceID,
+		ServiceName:   s.ServiceName,
+		UserID:        s.UserID,
+		Price:         s.PriceAt(Today()),
+		Currency:      s.Currency,
+		BillingPeriod: s.BillingPeriod,
+		StartDate:     s.StartDate.Format(DateFormat),
+		DeletedAt:     s.DeletedAt,
+		Version:       s.Version,
+		IntroPrice:    s.IntroPrice,
+		Status:        s.Status(Today()),
+	}
+	if s.EndDate != nil {
+		endDate := s.EndDate.Format(DateFormat)
+		resp.EndDate = &endDate
+	}
+	if s.TrialEnd != nil {
+		trialEnd := s.TrialEnd.Format(DateFormat)
+		resp.TrialEnd = &trialEnd
+	}
+	if s.IntroUntil != nil {
+		introUntil := s.IntroUntil.Format(DateFormat)
+		resp.IntroUntil = &introUntil
+	}
+	for _, p := range s.PriceHistory() {
+		resp.Prices = append(resp.Prices, p.ToResponse())
+	}
+	for _, p := range s.Pauses {
+		resp.Pauses = append(resp.Pauses, p.ToResponse())
+	}
+	return resp
+}
+
+func (s SubscriptionModel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToResponse())
+}
+
+// Validate checks the invariants that must hold for a stored subscription.
+func (s *SubscriptionModel) Validate() error {
+	if s.EndDate != nil && s.EndDate.Before(s.StartDate) {
+		return merrors.NewValidationError("end_date must be after start_date")
+	}
+	return validateIntro(s.StartDate, s.TrialEnd, s.IntroPrice, s.IntroUntil)
 }
 
 type SubscriptionCreateReq struct {
-	ServiceName string    `json:"service_name" validate:"required"`
+	// ServiceID references the catalog entry. Without it, ServiceName is resolved against the
+	// catalog's names and aliases, and a new entry is added if there is no match.
+	ServiceID   int64     `json:"service_id,omitempty"`
+	ServiceName string    `json:"service_name,omitempty" validate:"required_without=ServiceID"`
 	UserID      uuid.UUID `json:"user_id" validate:"required"`
-	Price       float64   `json:"price" validate:"required,gt=0"`
-	StartDate   string    `json:"start_date" validate:"required"`
-	EndDate     *string   `json:"end_date,omitempty"`
+	// Price and Currency default to the service's default price.
+	Price    Money  `json:"price,omitempty" validate:"omitempty,gt=0" swaggertype:"string" example:"399.99"`
+	Currency string `json:"currency,omitempty" validate:"omitempty,iso4217" example:"RUB"`
+	// BillingPeriod defaults to P1M (monthly).
+	BillingPeriod BillingPeriod `json:"billing_period,omitempty" swaggertype:"string" example:"P1Y"`
+	// StartDate and EndDate are YYYY-MM-DD, or MM-YYYY meaning the first and the last day
+	// of the month respectively. EndDate is inclusive.
+	StartDate string  `json:"start_date" validate:"required" example:"2025-07-17"`
+	EndDate   *string `json:"end_date,omitempty" example:"2025-12-16"`
+	// TrialEnd is the last day of a free trial, in the format of EndDate.
+	TrialEnd *string `json:"trial_end,omitempty" example:"2025-08-16"`
+	// IntroPrice is charged instead of Price after the trial, up to IntroUntil inclusive.
+	IntroPrice *Money  `json:"intro_price,omitempty" swaggertype:"string" example:"99.00"`
+	IntroUntil *string `json:"intro_until,omitempty" example:"2025-10-16"`
 }
 
 var validate *validator.Validate
@@ -35,63 +143,107 @@ func init() {
 
 func (s *SubscriptionCreateReq) Validate() error {
 	if err := validate.Struct(s); err != nil {
-		return err
+		return merrors.NewValidationError(err.Error())
 	}
 
 	if s.UserID == uuid.Nil {
 		return merrors.NewValidationError("user_id cannot be nil")
 	}
 
-	startDate, err := time.Parse(TimeFormat, s.StartDate)
+	if s.BillingPeriod != "" {
+		if err := s.BillingPeriod.Validate(); err != nil {
+			return err
+		}
+	}
+
+	startDate, err := ParseStartDate("start_date", s.StartDate)
 	if err != nil {
-		return merrors.NewValidationError("invalid start_date format (expected MM-YYYY)")
+		return err
 	}
 
 	if s.EndDate != nil {
-		endDate, err := time.Parse(TimeFormat, *s.EndDate)
+		endDate, err := ParseEndDate("end_date", *s.EndDate)
 		if err != nil {
-			return merrors.NewValidationError("invalid end_date format (expected MM-YYYY)")
+			return err
 		}
 		if endDate.Before(startDate) {
 			return merrors.NewValidationError("end_date must be after start_date")
 		}
 	}
 
-	return nil
+	trialEnd, err := parseOptionalEndDate("trial_end", s.TrialEnd)
+	if err != nil {
+		return err
+	}
+	introUntil, err := parseOptionalEndDate("intro_until", s.IntroUntil)
+	if err != nil {
+		return err
+	}
+	return validateIntro(startDate, trialEnd, s.IntroPrice, introUntil)
 }
 
 func (s *SubscriptionCreateReq) ToModel() (*SubscriptionModel, error) {
 	subModel := &SubscriptionModel{
-		ServiceName: s.ServiceName,
-		UserID:      s.UserID,
-		Price:       s.Price,
+		ServiceID:     s.ServiceID,
+		ServiceName:   s.ServiceName,
+		UserID:        s.UserID,
+		Price:         s.Price,
+		Currency:      s.Currency,
+		BillingPeriod: s.BillingPeriod,
+		IntroPrice:    s.IntroPrice,
+	}
+	if subModel.Currency == "" {
+		subModel.Currency = DefaultCurrency
+	}
+	if subModel.BillingPeriod == "" {
+		subModel.BillingPeriod = DefaultBillingPeriod
 	}
 
 	var err error
 	if s.StartDate != "" {
-		subModel.StartDate, err = time.Parse(TimeFormat, s.StartDate)
+		subModel.StartDate, err = ParseStartDate("start_date", s.StartDate)
 		if err != nil {
-			return nil, merrors.NewValidationError("invalid start_date format (expected MM-YYYY)")
+			return nil, err
 		}
 	}
 
 	if s.EndDate != nil {
-		endDate, err := time.Parse(TimeFormat, *s.EndDate)
+		endDate, err := ParseEndDate("end_date", *s.EndDate)
 		if err != nil {
-			return nil, merrors.NewValidationError("invalid start_date format (expected MM-YYYY)")
+			return nil, err
 		}
 		subModel.EndDate = &endDate
 	}
 
+	if subModel.TrialEnd, err = parseOptionalEndDate("trial_end", s.TrialEnd); err != nil {
+		return nil, err
+	}
+	if subModel.IntroUntil, err = parseOptionalEndDate("intro_until", s.IntroUntil); err != nil {
+		return nil, err
+	}
+
 	return subModel, nil
 }
 
+// SubscriptionUpdateReq patches a subscription. When both ServiceID and ServiceName are set,
+// ServiceID wins.
 type SubscriptionUpdateReq struct {
-	ServiceName *string    `json:"service_name"`
-	UserID      *uuid.UUID `json:"user_id"`
-	Price       *float64   `json:"price"`
-	StartDate   *string    `json:"start_date"`
-	EndDate     *string    `json:"end_date"`
+	ServiceID     *int64         `json:"service_id"`
+	ServiceName   *string        `json:"service_name"`
+	UserID        *uuid.UUID     `json:"user_id"`
+	Price         *Money         `json:"price" swaggertype:"string" example:"399.99"`
+	Currency      *string        `json:"currency" example:"RUB"`
+	BillingPeriod *BillingPeriod `json:"billing_period" swaggertype:"string" example:"P1Y"`
+	StartDate     *string        `json:"start_date" example:"2025-07-17"`
+	EndDate       *string        `json:"end_date" example:"2025-12-16"`
+	// EffectiveFrom makes a price change take effect from that day (YYYY-MM-DD or MM-YYYY) on,
+	// keeping the earlier prices. Without it the new price replaces the whole price history.
+	EffectiveFrom *string `json:"effective_from" example:"2025-09-01"`
+	// TrialEnd and IntroUntil set the trial and the introductory pricing; an empty string removes
+	// them. IntroPrice and IntroUntil must end up set together.
+	TrialEnd   *string `json:"trial_end" example:"2025-08-16"`
+	IntroPrice *Money  `json:"intro_price" swaggertype:"string" example:"99.00"`
+	IntroUntil *string `json:"intro_until" example:"2025-10-16"`
 }
 
 func (s *SubscriptionUpdateReq) Validate() error {
@@ -99,19 +251,46 @@ func (s *SubscriptionUpdateReq) Validate() error {
 		return merrors.NewValidationError("user_id cannot be nil")
 	}
 
+	if s.Price != nil && *s.Price <= 0 {
+		return merrors.NewValidationError("price must be greater than 0")
+	}
+
+	if s.EffectiveFrom != nil {
+		if s.Price == nil {
+			return merrors.NewValidationError("effective_from requires price")
+		}
+		if s.Currency != nil {
+			return merrors.NewValidationError("currency changes apply to the whole price history and cannot have effective_from")
+		}
+		if _, err := ParseStartDate("effective_from", *s.EffectiveFrom); err != nil {
+			return err
+		}
+	}
+
+	if s.Currency != nil {
+		if err := ValidateCurrency(*s.Currency); err != nil {
+			return err
+		}
+	}
+
+	if s.BillingPeriod != nil {
+		if err := s.BillingPeriod.Validate(); err != nil {
+			return err
+		}
+	}
+
 	var startDate, endDate time.Time
 	var err error
 	if s.StartDate != nil {
-		startDate, err = time.Parse(TimeFormat, *s.StartDate)
+		startDate, err = ParseStartDate("start_date", *s.StartDate)
 		if err != nil {
-			return merrors.NewValidationError("invalid start_date format (expected MM-YYYY)")
+			return err
 		}
-
 	}
 	if s.EndDate != nil {
-		endDate, err = time.Parse(TimeFormat, *s.EndDate)
+		endDate, err = ParseEndDate("end_date", *s.EndDate)
 		if err != nil {
-			return merrors.NewValidationError("invalid end_date format (expected MM-YYYY)")
+			return err
 		}
 	}
 
@@ -121,32 +300,81 @@ func (s *SubscriptionUpdateReq) Validate() error {
 		}
 	}
 
+	if s.IntroPrice != nil && *s.IntroPrice <= 0 {
+		return merrors.NewValidationError("intro_price must be greater than 0")
+	}
+	if s.TrialEnd != nil && *s.TrialEnd != "" {
+		if _, err := ParseEndDate("trial_end", *s.TrialEnd); err != nil {
+			return err
+		}
+	}
+	if s.IntroUntil != nil && *s.IntroUntil != "" {
+		if _, err := ParseEndDate("intro_until", *s.IntroUntil); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (s *SubscriptionUpdateReq) PatchModel(subModel *SubscriptionModel) error {
 	var err error
+	if s.ServiceID != nil {
+		subModel.ServiceID = *s.ServiceID
+	}
 	if s.ServiceName != nil {
 		subModel.ServiceName = *s.ServiceName
 	}
 	if s.UserID != nil {
 		subModel.UserID = *s.UserID
 	}
-	if s.Price != nil {
-		subModel.Price = *s.Price
+	if s.Currency != nil {
+		subModel.Currency = *s.Currency
+	}
+	if s.BillingPeriod != nil {
+		subModel.BillingPeriod = *s.BillingPeriod
 	}
 	if s.StartDate != nil {
-		subModel.StartDate, err = time.Parse(TimeFormat, *s.StartDate)
+		subModel.StartDate, err = ParseStartDate("start_date", *s.StartDate)
 		if err != nil {
-			return merrors.NewValidationError("invalid start_date format (expected MM-YYYY)")
+			return err
 		}
 	}
 	if s.EndDate != nil {
-		endDate, err := time.Parse(TimeFormat, *s.EndDate)
+		endDate, err := ParseEndDate("end_date", *s.EndDate)
 		if err != nil {
-			return merrors.NewValidationError("invalid start_date format (expected MM-YYYY)")
+			return err
 		}
 		subModel.EndDate = &endDate
 	}
+	if s.Price != nil {
+		var effectiveFrom time.Time
+		if s.EffectiveFrom != nil {
+			effectiveFrom, err = ParseStartDate("effective_from", *s.EffectiveFrom)
+			if err != nil {
+				return err
+			}
+		}
+		subModel.ChangePrice(*s.Price, effectiveFrom)
+	}
+	if s.TrialEnd != nil {
+		subModel.TrialEnd = nil
+		if *s.TrialEnd != "" {
+			if subModel.TrialEnd, err = parseOptionalEndDate("trial_end", s.TrialEnd); err != nil {
+				return err
+			}
+		}
+	}
+	if s.IntroPrice != nil {
+		subModel.IntroPrice = s.IntroPrice
+	}
+	if s.IntroUntil != nil {
+		subModel.IntroUntil = nil
+		if *s.IntroUntil == "" {
+			subModel.IntroPrice = nil
+		} else if subModel.IntroUntil, err = parseOptionalEndDate("intro_until", s.IntroUntil); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/google/uuid"
+)
+
+// subscriptionCSVFields set the field of SubscriptionCreateReq a CSV column is named after, by
+// its JSON name.
+var subscriptionCSVFields = map[string]func(req *SubscriptionCreateReq, value string) error{
+	"service_id": func(req *SubscriptionCreateReq, value string) error {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return merrors.NewValidationError(fmt.Sprintf("invalid service_id %q", value))
+		}
+		req.ServiceID = id
+		return nil
+	},
+	"service_name": func(req *SubscriptionCreateReq, value string) error {
+		req.ServiceName = csvUnescapeText(value)
+		return nil
+	},
+	"user_id": func(req *SubscriptionCreateReq, value string) error {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return merrors.NewValidationError(fmt.Sprintf("invalid user_id %q", value))
+		}
+		req.UserID = id
+		return nil
+	},
+	"price": func(req *SubscriptionCreateReq, value string) (err error) {
+		req.Price, err = ParseMoney(value)
+		return err
+	},
+	"currency": func(req *SubscriptionCreateReq, value string) error {
+		req.Currency = value
+		return nil
+	},
+	"billing_period": func(req *SubscriptionCreateReq, value string) error {
+		req.BillingPeriod = BillingPeriod(value)
+		return nil
+	},
+	"start_date": func(req *SubscriptionCreateReq, value string) error {
+		req.StartDate = value
+		return nil
+	},
+	"end_date": func(req *SubscriptionCreateReq, value string) error {
+		req.EndDate = &value
+		return nil
+	},
+	"trial_end": func(req *SubscriptionCreateReq, value string) error {
+		req.TrialEnd = &value
+		return nil
+	},
+	"intro_price": func(req *SubscriptionCreateReq, value string) error {
+		price, err := ParseMoney(value)
+		if err != nil {
+			return err
+		}
+		req.IntroPrice = &price
+		return nil
+	},
+	"intro_until": func(req *SubscriptionCreateReq, value string) error {
+		req.IntroUntil = &value
+		return nil
+	},
+	// Columns of an export that a new subscription does not take are ignored, so that an
+	// export can be imported back.
+	"id":         nil,
+	"deleted_at": nil,
+	"version":    nil,
+}
+
+// SubscriptionCSVReader reads subscription creation requests from CSV, one per row. The header
+// row names the columns after the JSON fields of SubscriptionCreateReq, in any order; empty
+// cells are left unset. The files written by SubscriptionCSVWriter can be read back.
+type SubscriptionCSVReader struct {
+	csv    *csv.Reader
+	fields []func(req *SubscriptionCreateReq, value string) error
+}
+
+// NewSubscriptionCSVReader reads the header row from r. It fails with a ValidationError if the
+// header is missing or names an unknown column.
+func NewSubscriptionCSVReader(r io.Reader) (*SubscriptionCSVReader, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, merrors.NewValidationError("CSV is empty, expected a header row")
+	}
+	if err != nil {
+		return nil, merrors.NewValidationError(fmt.Sprintf("invalid CSV header: %v", err))
+	}
+
+	fields := make([]func(*SubscriptionCreateReq, string) error, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // spreadsheets often start UTF-8 files with a BOM
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		set, ok := subscriptionCSVFields[name]
+		if !ok {
+			return nil, merrors.NewValidationError(fmt.Sprintf("unknown CSV column %q", name))
+		}
+		if seen[name] {
+			return nil, merrors.NewValidationError(fmt.Sprintf("duplicate CSV column %q", name))
+		}
+		seen[name] = true
+		fields[i] = set
+	}
+
+	return &SubscriptionCSVReader{csv: reader, fields: fields}, nil
+}
+
+// Read returns the next row as a request, along with the line of the file it starts on. A row
+// that cannot be parsed is reported with a ValidationError and skipped; io.EOF is returned after
+// the last row. The request is not validated.
+func (r *SubscriptionCSVReader) Read() (int, *SubscriptionCreateReq, error) {
+	record, err := r.csv.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.StartLine, nil, merrors.NewValidationError(parseErr.Err.Error())
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	line, _ := r.csv.FieldPos(0)
+	req := &SubscriptionCreateReq{}
+	for i, value := range record {
+		if value = strings.TrimSpace(value); value == "" || r.fields[i] == nil {
+			continue
+		}
+		if err := r.fields[i](req, value); err != nil {
+			return line, nil, err
+		}
+	}
+
+	return line, req, nil
+}
+
+// csvEscapeText neutralises free text that a spreadsheet would take for a formula, by prefixing
+// it with a quote that spreadsheets hide. csvUnescapeText undoes it, so exports import back.
+func csvEscapeText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@'", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func csvUnescapeText(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune("=+-@'", rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+// subscriptionCSVHeader is the header row written by SubscriptionCSVWriter.
+var subscriptionCSVHeader = []string{"id", "service_id", "service_name", "user_id", "price", "currency", "billing_period", "start_date", "end_date", "deleted_at", "version",
+	"trial_end", "intro_price", "intro_until"}
+
+// SubscriptionCSVWriter writes subscriptions as CSV rows under a header row, with the values
+// formatted as in SubscriptionResp and service names escaped with csvEscapeText.
+type SubscriptionCSVWriter struct {
+	csv    *csv.Writer
+	record []string
+}
+
+// NewSubscriptionCSVWriter returns a writer that starts with the header row.
+func NewSubscriptionCSVWriter(w io.Writer) (*SubscriptionCSVWriter, error) {
+	writer := &SubscriptionCSVWriter{csv: csv.NewWriter(w), record: make([]string, len(subscriptionCSVHeader))}
+	if err := writer.csv.Write(subscriptionCSVHeader); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *SubscriptionCSVWriter) Write(s *SubscriptionModel) error {
+	resp := s.ToResponse()
+	var endDate, deletedAt, trialEnd, introPrice, introUntil string
+	if resp.EndDate != nil {
+		endDate = *resp.EndDate
+	}
+	if resp.TrialEnd != nil {
+		trialEnd = *resp.TrialEnd
+	}
+	if resp.IntroPrice != nil {
+		introPrice = resp.IntroPrice.String()
+	}
+	if resp.IntroUntil != nil {
+		introUntil = *resp.IntroUntil
+	}
+	if resp.DeletedAt != nil {
+		deletedAt = resp.DeletedAt.Format(time.RFC3339Nano)
+	}
+
+	w.record = append(w.record[:0],
+		strconv.FormatInt(resp.ID, 10),
+		strconv.FormatInt(resp.ServiceID, 10),
+		csvEscapeText(resp.ServiceName),
+		resp.UserID.String(),
+		resp.Price.String(),
+		resp.Currency,
+		string(resp.BillingPeriod),
+		resp.StartDate,
+		endDate,
+		deletedAt,
+		strconv.FormatInt(resp.Version, 10),
+		trialEnd,
+		introPrice,
+		introUntil,
+	)
+	return w.csv.Write(w.record)
+}
+
+// Flush writes any buffered rows to the underlying writer.
+func (w *SubscriptionCSVWriter) Flush() error {
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// ImportLineError reports a row of an imported file that was not imported.
+type ImportLineError struct {
+	Line  int    `json:"line" example:"3"`
+	Error string `json:"error" example:"invalid user_id \"42\""`
+}
+
+// ImportReport is the outcome of a subscription import.
+type ImportReport struct {
+	DryRun bool `json:"dry_run"`
+	Rows   int  `json:"rows"`
+	// Imported counts the rows that were imported, or would have been in a dry run.
+	Imported int                `json:"imported"`
+	Failed   int                `json:"failed"`
+	Errors   []*ImportLineError `json:"errors"`
+	// Through is the line of the last row handled. Rows up to it that are not in Errors were
+	// imported; rows after it were not. It is short of the end of the file only if the import
+	// stopped early because of Error.
+	Through int    `json:"through" example:"1042"`
+	Error   string `json:"error,omitempty" example:"context canceled"`
+}
+
+// Fail records that the row starting on line was not imported because of err.
+func (r *ImportReport) Fail(line int, err error) {
+	r.Failed++
+	r.Errors = append(r.Errors, &ImportLineError{Line: line, Error: merrors.ErrorToResponseString(err)})
+}
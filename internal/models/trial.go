@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+)
+
+// StatusTrialing is the status of a subscription whose free trial is running.
+const StatusTrialing = "trialing"
+
+// Trialing reports whether the free trial of the subscription runs on day.
+func (s *SubscriptionModel) Trialing(day time.Time) bool {
+	return s.TrialEnd != nil && !day.Before(s.StartDate) && !day.After(*s.TrialEnd)
+}
+
+// priceOn returns the price charged on a billing date: nothing while paused or up to TrialEnd,
+// IntroPrice up to IntroUntil, and the price in force after that.
+func (s *SubscriptionModel) priceOn(date time.Time) Money {
+	if s.Paused(date) || s.TrialEnd != nil && !date.After(*s.TrialEnd) {
+		return 0
+	}
+	if s.IntroPrice != nil && s.IntroUntil != nil && !date.After(*s.IntroUntil) {
+		return *s.IntroPrice
+	}
+	return s.PriceAt(date)
+}
+
+// validateIntro checks the free trial and the introductory pricing of a subscription starting on
+// start. Each of them is optional, but IntroPrice and IntroUntil go together and the introductory
+// pricing must outlast the trial.
+func validateIntro(start time.Time, trialEnd *time.Time, introPrice *Money, introUntil *time.Time) error {
+	if trialEnd != nil && trialEnd.Before(start) {
+		return merrors.NewValidationError("trial_end must be after start_date")
+	}
+	if (introPrice == nil) != (introUntil == nil) {
+		return merrors.NewValidationError("intro_price and intro_until must be set together")
+	}
+	if introPrice != nil && *introPrice <= 0 {
+		return merrors.NewValidationError("intro_price must be greater than 0")
+	}
+	if introUntil != nil {
+		if introUntil.Before(start) {
+			return merrors.NewValidationError("intro_until must be after start_date")
+		}
+		if trialEnd != nil && !introUntil.After(*trialEnd) {
+			return merrors.NewValidationError("intro_until must be after trial_end")
+		}
+	}
+	return nil
+}
+
+// parseOptionalEndDate parses an optional end date with ParseEndDate.
+func parseOptionalEndDate(field string, s *string) (*time.Time, error) {
+	if s == nil {
+		return nil, nil
+	}
+	d, err := ParseEndDate(field, *s)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
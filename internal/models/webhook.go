@@ -0,0 +1,153 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+)
+
+// WebhookEvents are the subscription actions webhooks can receive. Purges are not delivered.
+var WebhookEvents = []string{ActionCreated, ActionUpdated, ActionDeleted, ActionRestored}
+
+// Statuses of webhook deliveries.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// DeliveryDead is a delivery that failed too many times and is no longer retried.
+	DeliveryDead = "dead"
+)
+
+// MaxWebhookBackoff caps the delay between two attempts of a delivery.
+const MaxWebhookBackoff = 6 * time.Hour
+
+// WebhookModel is an endpoint that subscription events are posted to.
+type WebhookModel struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url" example:"https://example.com/hooks/subscriptions"`
+	// Secret signs the deliveries. It is write-only.
+	Secret string `json:"-"`
+	// Events are the actions the webhook receives; empty means all of WebhookEvents.
+	Events    []string  `json:"events" example:"created,deleted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Receives reports whether the webhook is sent events of the action.
+func (w *WebhookModel) Receives(action string) bool {
+	if !slices.Contains(WebhookEvents, action) {
+		return false
+	}
+	return len(w.Events) == 0 || slices.Contains(w.Events, action)
+}
+
+type WebhookCreateReq struct {
+	URL string `json:"url" validate:"required,http_url" example:"https://example.com/hooks/subscriptions"`
+	// Secret is the HMAC-SHA256 key the deliveries are signed with.
+	Secret string   `json:"secret" validate:"required,min=16" example:"7f3a9c2e5b8d4f1a"`
+	Events []string `json:"events,omitempty" validate:"dive,oneof=created updated deleted restored" example:"created,deleted"`
+}
+
+func (w *WebhookCreateReq) Validate() error {
+	if err := validate.Struct(w); err != nil {
+		return merrors.NewValidationError(err.Error())
+	}
+	return nil
+}
+
+func (w *WebhookCreateReq) ToModel() *WebhookModel {
+	webhook := &WebhookModel{URL: w.URL, Secret: w.Secret, Events: []string{}}
+	for _, event := range w.Events {
+		if !slices.Contains(webhook.Events, event) {
+			webhook.Events = append(webhook.Events, event)
+		}
+	}
+	return webhook
+}
+
+// WebhookDelivery is an event on its way to a webhook. Deliveries are written to the outbox in the
+// same transaction as the change they announce and sent from there until the webhook accepts them
+// or they run out of attempts.
+type WebhookDelivery struct {
+	ID        int64  `json:"id"`
+	WebhookID int64  `json:"webhook_id"`
+	EventID   int64  `json:"event_id"`
+	Action    string `json:"action" example:"updated"`
+	// Payload is the SubscriptionEvent, as posted to the webhook.
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	Status        string          `json:"status" example:"pending"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty" example:"webhook responded with 503 Service Unavailable"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+
+	// URL and Secret of the webhook, set on claimed deliveries.
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// EventType is what the delivery's event is announced as, e.g. "subscription.updated".
+func (d *WebhookDelivery) EventType() string {
+	return "subscription." + d.Action
+}
+
+// Delivered records a successful attempt at now.
+func (d *WebhookDelivery) Delivered(now time.Time) {
+	d.Attempts++
+	d.Status = DeliveryDelivered
+	d.LastError = ""
+	d.DeliveredAt = &now
+}
+
+// Failed records a failed attempt at now. The delivery is retried after backoff, doubled for every
+// previous attempt up to MaxWebhookBackoff, unless it made maxAttempts attempts and is now dead.
+func (d *WebhookDelivery) Failed(now time.Time, err error, maxAttempts int, backoff time.Duration) {
+	d.Attempts++
+	d.LastError = err.Error()
+	if d.Attempts >= maxAttempts {
+		d.Status = DeliveryDead
+		return
+	}
+
+	delay := backoff
+	for i := 1; i < d.Attempts && delay < MaxWebhookBackoff; i++ {
+		delay *= 2
+	}
+	d.NextAttemptAt = now.Add(min(delay, MaxWebhookBackoff))
+}
+
+// Redeliver queues the delivery to be sent again at now with a fresh set of attempts, whatever
+// became of it so far.
+func (d *WebhookDelivery) Redeliver(now time.Time) {
+	d.Status = DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = now
+	d.LastError = ""
+	d.DeliveredAt = nil
+}
+
+// ValidateDeliveryStatus checks a delivery status filter, where empty means any status.
+func ValidateDeliveryStatus(status string) error {
+	switch status {
+	case "", DeliveryPending, DeliveryDelivered, DeliveryDead:
+		return nil
+	}
+	return merrors.NewValidationError(fmt.Sprintf("unknown delivery status %q", status))
+}
+
+// SignWebhook returns the signature of a delivery body sent at timestamp: "sha256=" followed by the
+// hex HMAC-SHA256, keyed with the webhook's secret, of the Unix timestamp, a dot and the body.
+// Signing the timestamp lets receivers reject replays of old deliveries.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,133 @@
+// Package reminders sends renewal and ending reminders for subscriptions in the background.
+package reminders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+)
+
+// Notifier delivers reminders.
+type Notifier interface {
+	Notify(ctx context.Context, reminder *models.Reminder) error
+}
+
+// LogNotifier writes reminders to the log.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, reminder *models.Reminder) error {
+	slog.InfoContext(ctx, "Subscription reminder", "kind", reminder.Kind, "subscription_id", reminder.SubscriptionID,
+		"user_id", reminder.UserID, "service_name", reminder.ServiceName, "date", reminder.Date, "amount", reminder.Amount, "currency", reminder.Currency)
+	return nil
+}
+
+// WebhookNotifier posts reminders as JSON to a URL. Any response other than 2xx is an error.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookNotifier(url string, client *http.Client) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: client}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, reminder *models.Reminder) error {
+	body, err := json.Marshal(reminder)
+	if err != nil {
+		return fmt.Errorf("failed to encode reminder: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build reminder webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post reminder webhook: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("reminder webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// SMTPNotifier mails reminders through an SMTP server without authentication or TLS, such as a
+// local relay or a development mail catcher like Mailpit. Subscriptions only know their user by
+// id, so every reminder goes to the same address.
+type SMTPNotifier struct {
+	Addr string
+	From string
+	To   string
+}
+
+func NewSMTPNotifier(addr, from, to string) *SMTPNotifier {
+	return &SMTPNotifier{Addr: addr, From: from, To: to}
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, reminder *models.Reminder) error {
+	subject := fmt.Sprintf("%s ends on %s", reminder.ServiceName, reminder.Date)
+	text := fmt.Sprintf("The subscription %d of user %s to %s ends on %s.", reminder.SubscriptionID, reminder.UserID, reminder.ServiceName, reminder.Date)
+	if reminder.Kind == models.ReminderRenewal {
+		subject = fmt.Sprintf("%s renews on %s", reminder.ServiceName, reminder.Date)
+		text = fmt.Sprintf("The subscription %d of user %s to %s renews on %s for %s %s.",
+			reminder.SubscriptionID, reminder.UserID, reminder.ServiceName, reminder.Date, reminder.Amount, reminder.Currency)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\nTo: %s\r\nSubject: %s\r\n", n.From, n.To, subject)
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(text + "\r\n")
+
+	if err := n.send(ctx, msg.String()); err != nil {
+		return fmt.Errorf("failed to mail reminder: %w", err)
+	}
+	return nil
+}
+
+// send is smtp.SendMail without STARTTLS, bounded by ctx.
+func (n *SMTPNotifier) send(ctx context.Context, msg string) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", n.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	host, _, _ := net.SplitHostPort(n.Addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Mail(n.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(n.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,109 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/repo"
+)
+
+// notifyTimeout bounds the delivery of a single reminder.
+const notifyTimeout = 30 * time.Second
+
+// Scheduler periodically sends the reminders of subscriptions that renew or end within Days
+// days. Every reminder is claimed in the ReminderRepository before it is sent, so restarts and
+// concurrent schedulers do not send it twice; a reminder that fails to send is released and
+// retried on the next run.
+type Scheduler struct {
+	subscriptions repo.SubscriptionRepository
+	sent          repo.ReminderRepository
+	notifier      Notifier
+	days          int
+	interval      time.Duration
+}
+
+func NewScheduler(subscriptions repo.SubscriptionRepository, sent repo.ReminderRepository, notifier Notifier, days int, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		subscriptions: subscriptions,
+		sent:          sent,
+		notifier:      notifier,
+		days:          days,
+		interval:      interval,
+	}
+}
+
+// Run sends the due reminders right away and then every interval, until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := s.RunOnce(ctx, models.Today())
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Failed to send reminders", "sent", sent, "error", err)
+		} else if sent > 0 {
+			slog.Info("Sent reminders", "sent", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends the reminders due on today that were not sent yet, and returns how many it sent.
+// It keeps going past reminders that fail to send and returns the first such error.
+func (s *Scheduler) RunOnce(ctx context.Context, today time.Time) (int, error) {
+	filter := &models.SubscriptionFilter{From: today, Till: today.AddDate(0, 0, s.days), Sort: models.SortByID}
+
+	var due []*models.Reminder
+	err := s.subscriptions.Export(ctx, filter, func(sub *models.SubscriptionModel) error {
+		due = append(due, sub.Reminders(today, s.days)...)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find subscriptions to remind of: %w", err)
+	}
+
+	var sent int
+	var firstErr error
+	for _, reminder := range due {
+		ok, err := s.send(ctx, reminder)
+		if ok {
+			sent++
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return sent, firstErr
+}
+
+// send claims and sends the reminder, and reports whether it did. A reminder claimed before is
+// skipped.
+func (s *Scheduler) send(ctx context.Context, reminder *models.Reminder) (bool, error) {
+	claimed, err := s.sent.Claim(ctx, reminder)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	notifyCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	if err := s.notifier.Notify(notifyCtx, reminder); err != nil {
+		// The release must happen even if ctx is done, or the reminder would never be retried.
+		if releaseErr := s.sent.Release(context.WithoutCancel(ctx), reminder); releaseErr != nil {
+			slog.Error("Failed to release unsent reminder", "reminder", reminder.Key(), "error", releaseErr)
+		}
+		return false, fmt.Errorf("failed to send reminder %s: %w", reminder.Key(), err)
+	}
+	return true, nil
+}
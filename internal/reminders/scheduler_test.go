@@ -0,0 +1,120 @@
+package reminders_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/reminders"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/repo"
+	"github.com/google/uuid"
+)
+
+// recorder is a Notifier that records the keys of the reminders it got, failing while fail is set.
+type recorder struct {
+	keys []string
+	fail bool
+}
+
+func (r *recorder) Notify(ctx context.Context, reminder *models.Reminder) error {
+	if r.fail {
+		return errors.New("notifier is down")
+	}
+	r.keys = append(r.keys, reminder.Key())
+	return nil
+}
+
+func date(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(models.DateFormat, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func newSubscriptions(t *testing.T) *repo.MemorySubscriptionRepo {
+	t.Helper()
+	end, trialEnd := date(t, "2025-04-02"), date(t, "2025-04-30")
+	subscriptions := repo.NewMemorySubscriptionRepo()
+	for _, sub := range []*models.SubscriptionModel{
+		{ServiceName: "Netflix", StartDate: date(t, "2025-01-01")},
+		{ServiceName: "Spotify", StartDate: date(t, "2025-01-15")},
+		{ServiceName: "Okko", StartDate: date(t, "2025-01-10"), EndDate: &end},
+		{ServiceName: "Kinopoisk", StartDate: date(t, "2025-03-01"), TrialEnd: &trialEnd},
+	} {
+		sub.UserID, sub.Price, sub.Currency, sub.BillingPeriod = uuid.New(), 400, "RUB", models.DefaultBillingPeriod
+		if err := subscriptions.Create(t.Context(), sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return subscriptions
+}
+
+func TestScheduler_RunOnce(t *testing.T) {
+	subscriptions, sent, notifier := newSubscriptions(t), repo.NewMemoryReminderRepo(), &recorder{}
+	today := date(t, "2025-03-30")
+
+	n, err := reminders.NewScheduler(subscriptions, sent, notifier, 3, time.Hour).RunOnce(t.Context(), today)
+	if err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	want := []string{"renewal/1/2025-04-01", "ending/3/2025-04-02"}
+	if n != 2 || !slices.Equal(notifier.keys, want) {
+		t.Errorf("RunOnce() sent %d %v, want %v", n, notifier.keys, want)
+	}
+
+	// A restarted scheduler shares what was sent through the repository.
+	n, err = reminders.NewScheduler(subscriptions, sent, notifier, 3, time.Hour).RunOnce(t.Context(), today.AddDate(0, 0, 1))
+	if err != nil || n != 0 {
+		t.Errorf("RunOnce() after a restart = %d, %v, want nothing sent", n, err)
+	}
+}
+
+func TestScheduler_RetriesFailedReminders(t *testing.T) {
+	subscriptions, notifier := newSubscriptions(t), &recorder{fail: true}
+	scheduler := reminders.NewScheduler(subscriptions, repo.NewMemoryReminderRepo(), notifier, 3, time.Hour)
+	today := date(t, "2025-03-30")
+
+	if n, err := scheduler.RunOnce(t.Context(), today); err == nil || n != 0 {
+		t.Errorf("RunOnce() with a failing notifier = %d, %v, want an error", n, err)
+	}
+
+	notifier.fail = false
+	if n, err := scheduler.RunOnce(t.Context(), today); err != nil || n != 2 {
+		t.Errorf("RunOnce() after the notifier recovered = %d, %v, want 2 sent", n, err)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got models.Reminder
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	amount := models.Money(400)
+	reminder := &models.Reminder{Kind: models.ReminderRenewal, SubscriptionID: 1, Date: "2025-04-01", Amount: &amount, Currency: "RUB"}
+	if err := reminders.NewWebhookNotifier(srv.URL, srv.Client()).Notify(t.Context(), reminder); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got.Key() != reminder.Key() || got.Amount == nil || *got.Amount != amount {
+		t.Errorf("webhook got %+v, want %+v", got, reminder)
+	}
+
+	if err := reminders.NewWebhookNotifier(srv.URL+"/down", srv.Client()).Notify(t.Context(), reminder); err == nil {
+		t.Errorf("Notify() to a failing endpoint error = nil, want an error")
+	}
+}
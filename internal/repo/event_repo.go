@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+)
+
+// insertEvent appends the event to the audit log within tx, fills in its id and timestamp, and
+// queues its webhook deliveries.
+func insertEvent(ctx context.Context, tx *sql.Tx, event *models.SubscriptionEvent) error {
+	before, err := snapshotJSON(event.Before)
+	if err != nil {
+		return err
+	}
+	after, err := snapshotJSON(event.After)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO subscription_events (subscription_id, action, actor, before, after)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at`
+
+	err = tx.QueryRowContext(ctx, query, event.SubscriptionID, event.Action, event.Actor, before, after).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record subscription %s event: %w", event.Action, err)
+	}
+	return enqueueWebhooks(ctx, tx, event)
+}
+
+// snapshotJSON encodes a snapshot for a JSONB column, with nil as NULL.
+func snapshotJSON(snapshot *models.SubscriptionResp) (any, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode subscription snapshot: %w", err)
+	}
+	return string(data), nil
+}
+
+// GetHistory returns the audit log of the subscription, oldest first. The log outlives the
+// subscription, so the history of a deleted subscription is still available. Subscriptions
+// created before the log was introduced have an empty history.
+func (s *SubscriptionRepo) GetHistory(ctx context.Context, id int64) ([]*models.SubscriptionEvent, error) {
+	query := `
+        SELECT id, subscription_id, action, actor, created_at, before, after
+        FROM subscription_events
+        WHERE subscription_id = $1
+        ORDER BY id`
+
+	rows, err := s.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history of subscription %d: %w", id, err)
+	}
+	defer rows.Close()
+
+	events := []*models.SubscriptionEvent{}
+	for rows.Next() {
+		event := &models.SubscriptionEvent{}
+		var before, after []byte
+		if err := rows.Scan(&event.ID, &event.SubscriptionID, &event.Action, &event.Actor, &event.CreatedAt, &before, &after); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription event row: %w", err)
+		}
+		if event.Before, err = decodeSnapshot(before); err != nil {
+			return nil, err
+		}
+		if event.After, err = decodeSnapshot(after); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subscription event rows: %w", err)
+	}
+
+	if len(events) == 0 {
+		var exists bool
+		if err := s.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM subscriptions WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check subscription %d exists: %w", id, err)
+		}
+		if !exists {
+			return nil, merrors.NewNotFoundErr("subscription not found")
+		}
+	}
+	return events, nil
+}
+
+func decodeSnapshot(data []byte) (*models.SubscriptionResp, error) {
+	if data == nil {
+		return nil, nil
+	}
+	snapshot := &models.SubscriptionResp{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode subscription snapshot: %w", err)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,186 @@
+package repo
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+)
+
+// MemoryServiceRepo is a thread-safe in-memory ServiceRepository meant for tests. Given the
+// subscription repository, it also keeps subscriptions' service names in sync and refuses to
+// delete services that are in use, like the foreign key does in SQL.
+type MemoryServiceRepo struct {
+	mu            sync.RWMutex
+	lastID        int64
+	services      map[int64]*models.ServiceModel
+	subscriptions *MemorySubscriptionRepo
+}
+
+var _ ServiceRepository = (*MemoryServiceRepo)(nil)
+
+func NewMemoryServiceRepo(subscriptions *MemorySubscriptionRepo) *MemoryServiceRepo {
+	return &MemoryServiceRepo{
+		services:      make(map[int64]*models.ServiceModel),
+		subscriptions: subscriptions,
+	}
+}
+
+func cloneService(s *models.ServiceModel) *models.ServiceModel {
+	c := *s
+	c.Aliases = slices.Clone(s.Aliases)
+	if c.Aliases == nil {
+		c.Aliases = []string{}
+	}
+	if s.DefaultPrice != nil {
+		price := *s.DefaultPrice
+		c.DefaultPrice = &price
+	}
+	return &c
+}
+
+// checkNames returns a ConflictError if another service already uses one of the names.
+// The caller must hold m.mu.
+func (m *MemoryServiceRepo) checkNames(service *models.ServiceModel) error {
+	for _, other := range m.services {
+		if other.ID == service.ID {
+			continue
+		}
+		for _, name := range service.Names() {
+			if (&models.ServiceFilter{Name: name}).Matches(other) {
+				return merrors.NewConflictError(fmt.Sprintf("service name %q is already taken", name))
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MemoryServiceRepo) Create(ctx context.Context, service *models.ServiceModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkNames(service); err != nil {
+		return err
+	}
+
+	m.lastID++
+	service.ID = m.lastID
+	m.services[service.ID] = cloneService(service)
+
+	return nil
+}
+
+func (m *MemoryServiceRepo) GetByID(ctx context.Context, id int64) (*models.ServiceModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	service, ok := m.services[id]
+	if !ok {
+		return nil, merrors.NewNotFoundErr("service not found")
+	}
+	return cloneService(service), nil
+}
+
+func (m *MemoryServiceRepo) GetByName(ctx context.Context, name string) (*models.ServiceModel, error) {
+	services, err := m.List(ctx, &models.ServiceFilter{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	if len(services) == 0 {
+		return nil, merrors.NewNotFoundErr("service not found")
+	}
+	return services[0], nil
+}
+
+func (m *MemoryServiceRepo) List(ctx context.Context, filters *models.ServiceFilter) ([]*models.ServiceModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	services := []*models.ServiceModel{}
+	for _, service := range m.services {
+		if filters.Matches(service) {
+			services = append(services, cloneService(service))
+		}
+	}
+	slices.SortFunc(services, func(a, b *models.ServiceModel) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return services, nil
+}
+
+func (m *MemoryServiceRepo) Update(ctx context.Context, service *models.ServiceModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.services[service.ID]; !ok {
+		return merrors.NewNotFoundErr("service not found")
+	}
+	if err := m.checkNames(service); err != nil {
+		return err
+	}
+	m.services[service.ID] = cloneService(service)
+
+	if m.subscriptions != nil {
+		m.subscriptions.mu.Lock()
+		defer m.subscriptions.mu.Unlock()
+		for _, id := range slices.Sorted(maps.Keys(m.subscriptions.subscriptions)) {
+			subscription := m.subscriptions.subscriptions[id]
+			if subscription.ServiceID != service.ID || subscription.ServiceName == service.Name {
+				continue
+			}
+			before := cloneSubscription(subscription)
+			subscription.ServiceName = service.Name
+			subscription.Version++
+			m.subscriptions.record(ctx, models.ActionUpdated, before, subscription)
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryServiceRepo) Delete(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.services[id]; !ok {
+		return merrors.NewNotFoundErr("service not found")
+	}
+
+	if m.subscriptions != nil {
+		m.subscriptions.mu.RLock()
+		defer m.subscriptions.mu.RUnlock()
+		for _, subscription := range m.subscriptions.subscriptions {
+			if subscription.ServiceID == id {
+				return merrors.NewConflictError("service has subscriptions")
+			}
+		}
+	}
+	delete(m.services, id)
+
+	return nil
+}
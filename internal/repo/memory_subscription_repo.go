@@ -0,0 +1,366 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+)
+
+// MemorySubscriptionRepo is a thread-safe in-memory SubscriptionRepository.
+// It follows the same filter and pagination semantics as SubscriptionRepo and is meant for tests.
+type MemorySubscriptionRepo struct {
+	mu            sync.RWMutex
+	lastID        int64
+	subscriptions map[int64]*models.SubscriptionModel
+	events        []*models.SubscriptionEvent
+	// outbox, when set by NewMemoryWebhookRepo, gets the deliveries of recorded events.
+	outbox *MemoryWebhookRepo
+}
+
+var _ SubscriptionRepository = (*MemorySubscriptionRepo)(nil)
+
+func NewMemorySubscriptionRepo() *MemorySubscriptionRepo {
+	return &MemorySubscriptionRepo{subscriptions: make(map[int64]*models.SubscriptionModel)}
+}
+
+func cloneSubscription(s *models.SubscriptionModel) *models.SubscriptionModel {
+	c := *s
+	if s.EndDate != nil {
+		endDate := *s.EndDate
+		c.EndDate = &endDate
+	}
+	if s.DeletedAt != nil {
+		deletedAt := *s.DeletedAt
+		c.DeletedAt = &deletedAt
+	}
+	if s.TrialEnd != nil {
+		trialEnd := *s.TrialEnd
+		c.TrialEnd = &trialEnd
+	}
+	if s.IntroPrice != nil {
+		introPrice := *s.IntroPrice
+		c.IntroPrice = &introPrice
+	}
+	if s.IntroUntil != nil {
+		introUntil := *s.IntroUntil
+		c.IntroUntil = &introUntil
+	}
+	c.Prices = slices.Clone(s.Prices)
+	c.Pauses = slices.Clone(s.Pauses)
+	for i, p := range c.Pauses {
+		if p.Until != nil {
+			until := *p.Until
+			c.Pauses[i].Until = &until
+		}
+	}
+	return &c
+}
+
+func (m *MemorySubscriptionRepo) Create(ctx context.Context, subscription *models.SubscriptionModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.create(ctx, subscription)
+	return nil
+}
+
+func (m *MemorySubscriptionRepo) CreateBatch(ctx context.Context, subscriptions []*models.SubscriptionModel, atomic bool) ([]error, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.batch(ctx, len(subscriptions), atomic, func(i int) error {
+		m.create(ctx, subscriptions[i])
+		return nil
+	})
+}
+
+// create stores a new subscription. The caller must hold m.mu for writing.
+func (m *MemorySubscriptionRepo) create(ctx context.Context, subscription *models.SubscriptionModel) {
+	m.lastID++
+	subscription.ID = m.lastID
+	subscription.Version = 1
+	subscription.DeletedAt = nil
+	m.subscriptions[subscription.ID] = cloneSubscription(subscription)
+	m.record(ctx, models.ActionCreated, nil, subscription)
+}
+
+// batch mirrors batchTx: it applies n items with apply and, in atomic mode, undoes them all once
+// one fails. Like a database sequence, ids handed out are not reused. The caller must hold m.mu
+// for writing.
+func (m *MemorySubscriptionRepo) batch(ctx context.Context, n int, atomic bool, apply func(i int) error) ([]error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	subscriptions, events, deliveries := maps.Clone(m.subscriptions), len(m.events), m.outbox.size()
+	errs := make([]error, n)
+	for i := range n {
+		if errs[i] = apply(i); errs[i] != nil && atomic {
+			m.subscriptions, m.events = subscriptions, m.events[:events]
+			m.outbox.truncate(deliveries)
+			models.AbortBatch(errs)
+			break
+		}
+	}
+	return errs, nil
+}
+
+// record appends an event to the audit log, queues its webhook deliveries and returns it. The
+// caller must hold m.mu for writing.
+func (m *MemorySubscriptionRepo) record(ctx context.Context, action string, before, after *models.SubscriptionModel) *models.SubscriptionEvent {
+	event := models.NewSubscriptionEvent(ctx, action, before, after)
+	event.ID = int64(len(m.events)) + 1
+	event.CreatedAt = time.Now().UTC()
+	m.events = append(m.events, event)
+	m.outbox.enqueue(event)
+	return event
+}
+
+// lockForChange returns the live subscription with the id, checking that it is still at version
+// unless version is zero. The caller must hold m.mu for writing.
+func (m *MemorySubscriptionRepo) lockForChange(id, version int64) (*models.SubscriptionModel, error) {
+	subscription, ok := m.subscriptions[id]
+	if !ok || subscription.DeletedAt != nil {
+		return nil, merrors.NewNotFoundErr("subscription not found")
+	}
+	if version != 0 && subscription.Version != version {
+		return nil, merrors.NewPreconditionFailedError(fmt.Sprintf("subscription is at version %d, not %d", subscription.Version, version))
+	}
+	return subscription, nil
+}
+
+// matching returns copies of all subscriptions matching the filter. The caller must hold m.mu.
+func (m *MemorySubscriptionRepo) matching(filters *models.SubscriptionFilter) []*models.SubscriptionModel {
+	var subscriptions []*models.SubscriptionModel
+	for _, subscription := range m.subscriptions {
+		if filters.Matches(subscription) {
+			subscriptions = append(subscriptions, cloneSubscription(subscription))
+		}
+	}
+	return subscriptions
+}
+
+func (m *MemorySubscriptionRepo) GetByFilters(ctx context.Context, filters *models.SubscriptionFilter) (*models.SubscriptionPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	subscriptions := m.matching(filters)
+	total := int64(len(subscriptions))
+
+	subscriptions = slices.DeleteFunc(subscriptions, func(s *models.SubscriptionModel) bool {
+		return !filters.AfterCursor(s)
+	})
+	slices.SortFunc(subscriptions, filters.Compare)
+	if uint64(len(subscriptions)) > filters.Limit+1 {
+		subscriptions = subscriptions[:filters.Limit+1]
+	}
+
+	return models.NewSubscriptionPage(filters, subscriptions, total)
+}
+
+func (m *MemorySubscriptionRepo) Export(ctx context.Context, filters *models.SubscriptionFilter, fn func(*models.SubscriptionModel) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	subscriptions := m.matching(filters)
+	m.mu.RUnlock()
+
+	slices.SortFunc(subscriptions, filters.Compare)
+	for _, subscription := range subscriptions {
+		if err := fn(subscription); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemorySubscriptionRepo) GetByID(ctx context.Context, ID int64) (*models.SubscriptionModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	subscription, ok := m.subscriptions[ID]
+	if !ok || subscription.DeletedAt != nil {
+		return nil, merrors.NewNotFoundErr("subscription not found")
+	}
+
+	return cloneSubscription(subscription), nil
+}
+
+func (m *MemorySubscriptionRepo) Update(ctx context.Context, subscription *models.SubscriptionModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.update(ctx, subscription)
+}
+
+func (m *MemorySubscriptionRepo) UpdateBatch(ctx context.Context, subscriptions []*models.SubscriptionModel, atomic bool) ([]error, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.batch(ctx, len(subscriptions), atomic, func(i int) error {
+		return m.update(ctx, subscriptions[i])
+	})
+}
+
+// update mirrors updateSubscription. The caller must hold m.mu for writing.
+func (m *MemorySubscriptionRepo) update(ctx context.Context, subscription *models.SubscriptionModel) error {
+	before, err := m.lockForChange(subscription.ID, subscription.Version)
+	if err != nil {
+		return err
+	}
+	subscription.Version = before.Version + 1
+	subscription.DeletedAt = nil
+	m.subscriptions[subscription.ID] = cloneSubscription(subscription)
+	m.record(ctx, models.ActionUpdated, before, subscription)
+
+	return nil
+}
+
+func (m *MemorySubscriptionRepo) Delete(ctx context.Context, id int64, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.delete(ctx, id, version)
+}
+
+func (m *MemorySubscriptionRepo) DeleteBatch(ctx context.Context, ids []int64, versions []int64, atomic bool) ([]error, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.batch(ctx, len(ids), atomic, func(i int) error {
+		var version int64
+		if versions != nil {
+			version = versions[i]
+		}
+		return m.delete(ctx, ids[i], version)
+	})
+}
+
+// delete mirrors deleteSubscription. The caller must hold m.mu for writing.
+func (m *MemorySubscriptionRepo) delete(ctx context.Context, id int64, version int64) error {
+	before, err := m.lockForChange(id, version)
+	if err != nil {
+		return err
+	}
+	deleted := cloneSubscription(before)
+	now := time.Now().UTC()
+	deleted.DeletedAt = &now
+	deleted.Version++
+	m.subscriptions[id] = deleted
+	m.record(ctx, models.ActionDeleted, before, nil)
+
+	return nil
+}
+
+func (m *MemorySubscriptionRepo) Restore(ctx context.Context, id int64, version int64) (*models.SubscriptionModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subscription, ok := m.subscriptions[id]
+	if !ok || subscription.DeletedAt == nil {
+		return nil, merrors.NewNotFoundErr("deleted subscription not found")
+	}
+	if version != 0 && subscription.Version != version {
+		return nil, merrors.NewPreconditionFailedError(fmt.Sprintf("subscription is at version %d, not %d", subscription.Version, version))
+	}
+	subscription.DeletedAt = nil
+	subscription.Version++
+	m.record(ctx, models.ActionRestored, nil, subscription)
+
+	return cloneSubscription(subscription), nil
+}
+
+func (m *MemorySubscriptionRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged int64
+	for id, subscription := range m.subscriptions {
+		if subscription.DeletedAt != nil && subscription.DeletedAt.Before(deletedBefore) {
+			delete(m.subscriptions, id)
+			m.record(ctx, models.ActionPurged, nil, nil).SubscriptionID = id
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+func (m *MemorySubscriptionRepo) GetSum(ctx context.Context, filters *models.SubscriptionFilter) (models.Amounts, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return models.TotalCost(ctx, m.matching(filters), filters.From, filters.Till)
+}
+
+func (m *MemorySubscriptionRepo) GetMonthlySums(ctx context.Context, filters *models.SubscriptionFilter) ([]*models.MonthlySum, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return models.NewMonthlySums(ctx, m.matching(filters), filters.From, filters.Till)
+}
+
+func (m *MemorySubscriptionRepo) GetHistory(ctx context.Context, id int64) ([]*models.SubscriptionEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := []*models.SubscriptionEvent{}
+	for _, event := range m.events {
+		if event.SubscriptionID == id {
+			c := *event
+			events = append(events, &c)
+		}
+	}
+	if _, ok := m.subscriptions[id]; !ok && len(events) == 0 {
+		return nil, merrors.NewNotFoundErr("subscription not found")
+	}
+	return events, nil
+}
@@ -0,0 +1,275 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+)
+
+// MemoryWebhookRepo is a thread-safe in-memory WebhookRepository meant for tests. Its outbox is fed
+// by the subscription repository it is created with, and rolled back along with atomic batches.
+// Deliveries of deleted webhooks are kept but ignored, so that rollbacks only ever truncate.
+type MemoryWebhookRepo struct {
+	mu         sync.Mutex
+	lastID     int64
+	webhooks   map[int64]*models.WebhookModel
+	deliveries []*models.WebhookDelivery
+}
+
+var _ WebhookRepository = (*MemoryWebhookRepo)(nil)
+
+// NewMemoryWebhookRepo returns a repository that queues deliveries for the changes of subscriptions.
+func NewMemoryWebhookRepo(subscriptions *MemorySubscriptionRepo) *MemoryWebhookRepo {
+	m := &MemoryWebhookRepo{webhooks: make(map[int64]*models.WebhookModel)}
+
+	subscriptions.mu.Lock()
+	defer subscriptions.mu.Unlock()
+	subscriptions.outbox = m
+
+	return m
+}
+
+func cloneWebhook(w *models.WebhookModel) *models.WebhookModel {
+	c := *w
+	c.Events = slices.Clone(w.Events)
+	return &c
+}
+
+func cloneDelivery(d *models.WebhookDelivery) *models.WebhookDelivery {
+	c := *d
+	return &c
+}
+
+// enqueue adds a pending delivery of the event for every webhook that receives it. It is called by
+// the subscription repository with its lock held, and does nothing on a nil repository.
+func (m *MemoryWebhookRepo) enqueue(event *models.SubscriptionEvent) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	payload, _ := json.Marshal(event)
+	now := time.Now().UTC()
+	for _, id := range slices.Sorted(maps.Keys(m.webhooks)) {
+		if !m.webhooks[id].Receives(event.Action) {
+			continue
+		}
+		m.deliveries = append(m.deliveries, &models.WebhookDelivery{
+			ID:            int64(len(m.deliveries)) + 1,
+			WebhookID:     id,
+			EventID:       event.ID,
+			Action:        event.Action,
+			Payload:       payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+}
+
+// size returns how many deliveries the outbox has, for truncate.
+func (m *MemoryWebhookRepo) size() int {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.deliveries)
+}
+
+// truncate drops the deliveries queued since the outbox had n of them.
+func (m *MemoryWebhookRepo) truncate(n int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = m.deliveries[:n]
+}
+
+func (m *MemoryWebhookRepo) Create(ctx context.Context, webhook *models.WebhookModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastID++
+	webhook.ID = m.lastID
+	webhook.CreatedAt = time.Now().UTC()
+	m.webhooks[webhook.ID] = cloneWebhook(webhook)
+	return nil
+}
+
+func (m *MemoryWebhookRepo) GetByID(ctx context.Context, id int64) (*models.WebhookModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	webhook, ok := m.webhooks[id]
+	if !ok {
+		return nil, merrors.NewNotFoundErr("webhook not found")
+	}
+	return cloneWebhook(webhook), nil
+}
+
+func (m *MemoryWebhookRepo) List(ctx context.Context) ([]*models.WebhookModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	webhooks := []*models.WebhookModel{}
+	for _, id := range slices.Sorted(maps.Keys(m.webhooks)) {
+		webhooks = append(webhooks, cloneWebhook(m.webhooks[id]))
+	}
+	return webhooks, nil
+}
+
+func (m *MemoryWebhookRepo) Delete(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.webhooks[id]; !ok {
+		return merrors.NewNotFoundErr("webhook not found")
+	}
+	delete(m.webhooks, id)
+	return nil
+}
+
+func (m *MemoryWebhookRepo) ListDeliveries(ctx context.Context, webhookID int64, status string) ([]*models.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.webhooks[webhookID]; !ok {
+		return nil, merrors.NewNotFoundErr("webhook not found")
+	}
+
+	deliveries := []*models.WebhookDelivery{}
+	for _, delivery := range slices.Backward(m.deliveries) {
+		if len(deliveries) == deliveriesLimit {
+			break
+		}
+		if delivery.WebhookID == webhookID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, cloneDelivery(delivery))
+		}
+	}
+	return deliveries, nil
+}
+
+func (m *MemoryWebhookRepo) GetDelivery(ctx context.Context, webhookID, id int64) (*models.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delivery := m.delivery(id)
+	if delivery == nil || delivery.WebhookID != webhookID {
+		return nil, merrors.NewNotFoundErr("webhook delivery not found")
+	}
+	return cloneDelivery(delivery), nil
+}
+
+// delivery returns the delivery with the id, or nil if there is none or its webhook was deleted.
+// The caller must hold m.mu.
+func (m *MemoryWebhookRepo) delivery(id int64) *models.WebhookDelivery {
+	if id < 1 || id > int64(len(m.deliveries)) {
+		return nil
+	}
+	delivery := m.deliveries[id-1]
+	if _, ok := m.webhooks[delivery.WebhookID]; !ok {
+		return nil
+	}
+	return delivery
+}
+
+func (m *MemoryWebhookRepo) ClaimDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	due := []*models.WebhookDelivery{}
+	for _, delivery := range m.deliveries {
+		webhook, ok := m.webhooks[delivery.WebhookID]
+		if !ok || delivery.Status != models.DeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		c := cloneDelivery(delivery)
+		c.URL, c.Secret = webhook.URL, webhook.Secret
+		due = append(due, c)
+	}
+
+	slices.SortStableFunc(due, func(a, b *models.WebhookDelivery) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+	due = due[:min(limit, len(due))]
+	for _, delivery := range due {
+		m.deliveries[delivery.ID-1].NextAttemptAt = now.Add(lease)
+	}
+	return due, nil
+}
+
+func (m *MemoryWebhookRepo) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := m.delivery(delivery.ID)
+	if stored == nil {
+		return merrors.NewNotFoundErr("webhook delivery not found")
+	}
+	stored.Status = delivery.Status
+	stored.Attempts = delivery.Attempts
+	stored.NextAttemptAt = delivery.NextAttemptAt
+	stored.LastError = delivery.LastError
+	stored.DeliveredAt = delivery.DeliveredAt
+	return nil
+}
+
+func (m *MemoryWebhookRepo) RedeliverDelivery(ctx context.Context, webhookID, id int64, now time.Time) (*models.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delivery := m.delivery(id)
+	if delivery == nil || delivery.WebhookID != webhookID {
+		return nil, merrors.NewNotFoundErr("webhook delivery not found")
+	}
+	if delivery.Status == models.DeliveryPending && delivery.NextAttemptAt.After(now) {
+		return nil, merrors.NewConflictError("webhook delivery is pending and not due yet, it may be being sent")
+	}
+	delivery.Redeliver(now)
+	return cloneDelivery(delivery), nil
+}
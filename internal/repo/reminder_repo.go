@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+)
+
+// ReminderRepository remembers which reminders were sent, so that each is sent once even across
+// restarts and replicas.
+type ReminderRepository interface {
+	// Claim records the reminder as sent and reports whether it was not already.
+	Claim(ctx context.Context, reminder *models.Reminder) (bool, error)
+	// Release forgets a claimed reminder that could not be sent, so that it is claimed again.
+	Release(ctx context.Context, reminder *models.Reminder) error
+}
+
+var _ ReminderRepository = (*ReminderRepo)(nil)
+
+// ReminderRepo stores sent reminders in the sent_reminders table.
+type ReminderRepo struct {
+	DB *sql.DB
+}
+
+func NewReminderRepo(db *sql.DB) *ReminderRepo {
+	return &ReminderRepo{DB: db}
+}
+
+func (r *ReminderRepo) Claim(ctx context.Context, reminder *models.Reminder) (bool, error) {
+	query := `
+        INSERT INTO sent_reminders (kind, subscription_id, due_date)
+        VALUES ($1, $2, $3::date)
+        ON CONFLICT DO NOTHING`
+
+	res, err := r.DB.ExecContext(ctx, query, reminder.Kind, reminder.SubscriptionID, reminder.Date)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim reminder %s: %w", reminder.Key(), err)
+	}
+	claimed, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim reminder %s: %w", reminder.Key(), err)
+	}
+	return claimed == 1, nil
+}
+
+func (r *ReminderRepo) Release(ctx context.Context, reminder *models.Reminder) error {
+	query := `
+        DELETE FROM sent_reminders
+        WHERE kind = $1 AND subscription_id = $2 AND due_date = $3::date`
+
+	if _, err := r.DB.ExecContext(ctx, query, reminder.Kind, reminder.SubscriptionID, reminder.Date); err != nil {
+		return fmt.Errorf("failed to release reminder %s: %w", reminder.Key(), err)
+	}
+	return nil
+}
+
+// MemoryReminderRepo is a thread-safe in-memory ReminderRepository, meant for tests.
+type MemoryReminderRepo struct {
+	mu   sync.Mutex
+	sent map[string]bool
+}
+
+var _ ReminderRepository = (*MemoryReminderRepo)(nil)
+
+func NewMemoryReminderRepo() *MemoryReminderRepo {
+	return &MemoryReminderRepo{sent: make(map[string]bool)}
+}
+
+func (m *MemoryReminderRepo) Claim(ctx context.Context, reminder *models.Reminder) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sent[reminder.Key()] {
+		return false, nil
+	}
+	m.sent[reminder.Key()] = true
+	return true, nil
+}
+
+func (m *MemoryReminderRepo) Release(ctx context.Context, reminder *models.Reminder) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sent, reminder.Key())
+	return nil
+}
@@ -0,0 +1,213 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ServiceRepository is the storage of the service catalog.
+type ServiceRepository interface {
+	Create(ctx context.Context, service *models.ServiceModel) error
+	GetByID(ctx context.Context, id int64) (*models.ServiceModel, error)
+	// GetByName returns the service whose canonical name or alias matches name after normalization.
+	GetByName(ctx context.Context, name string) (*models.ServiceModel, error)
+	List(ctx context.Context, filters *models.ServiceFilter) ([]*models.ServiceModel, error)
+	Update(ctx context.Context, service *models.ServiceModel) error
+	Delete(ctx context.Context, id int64) error
+}
+
+var _ ServiceRepository = (*ServiceRepo)(nil)
+
+// ServiceRepo stores the catalog in the services table. Every name of a service, canonical
+// or alias, is a row of service_names keyed by its normalized form, which keeps names unique
+// across the whole catalog.
+type ServiceRepo struct {
+	DB *sql.DB
+}
+
+func NewServiceRepo(db *sql.DB) *ServiceRepo {
+	return &ServiceRepo{DB: db}
+}
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+func (s *ServiceRepo) Create(ctx context.Context, service *models.ServiceModel) error {
+	return withTx(ctx, s.DB, func(tx *sql.Tx) error {
+		query := `
+            INSERT INTO services (name, category, default_price, currency)
+            VALUES ($1, $2, $3, $4)
+            RETURNING id`
+
+		if err := tx.QueryRowContext(ctx, query, service.Name, service.Category, service.DefaultPrice, service.Currency).Scan(&service.ID); err != nil {
+			return fmt.Errorf("failed to create service in database: %w", err)
+		}
+
+		return insertServiceNames(ctx, tx, service)
+	})
+}
+
+func insertServiceNames(ctx context.Context, tx *sql.Tx, service *models.ServiceModel) error {
+	query := `
+        INSERT INTO service_names (lookup_key, service_id, name, position)
+        VALUES ($1, $2, $3, $4)`
+
+	for i, name := range service.Names() {
+		_, err := tx.ExecContext(ctx, query, models.NormalizeServiceName(name), service.ID, name, i)
+		if pgErrorCode(err) == pgUniqueViolation {
+			return merrors.NewConflictError(fmt.Sprintf("service name %q is already taken", name))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to store service name %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (s *ServiceRepo) selectBuilder() squirrel.SelectBuilder {
+	return squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).
+		Select("s.id, s.name, s.category, s.default_price, s.currency, n.name").
+		From("services s").
+		Join("service_names n ON n.service_id = s.id").
+		OrderBy("s.id", "n.position")
+}
+
+// query runs a select built by selectBuilder and folds the name rows into services.
+func (s *ServiceRepo) query(ctx context.Context, builder squirrel.SelectBuilder) ([]*models.ServiceModel, error) {
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SQL query for services: %w", err)
+	}
+	slog.Debug("Services query", "query", query, "args", args)
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute services query: %w", err)
+	}
+	defer rows.Close()
+
+	services := []*models.ServiceModel{}
+	for rows.Next() {
+		var (
+			service models.ServiceModel
+			name    string
+		)
+		if err := rows.Scan(&service.ID, &service.Name, &service.Category, &service.DefaultPrice, &service.Currency, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan service row: %w", err)
+		}
+
+		if len(services) == 0 || services[len(services)-1].ID != service.ID {
+			service.Aliases = []string{}
+			services = append(services, &service)
+		}
+		if last := services[len(services)-1]; name != last.Name {
+			last.Aliases = append(last.Aliases, name)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating service rows: %w", err)
+	}
+
+	return services, nil
+}
+
+func (s *ServiceRepo) getOne(ctx context.Context, builder squirrel.SelectBuilder) (*models.ServiceModel, error) {
+	services, err := s.query(ctx, builder)
+	if err != nil {
+		return nil, err
+	}
+	if len(services) == 0 {
+		return nil, merrors.NewNotFoundErr("service not found")
+	}
+	return services[0], nil
+}
+
+func (s *ServiceRepo) GetByID(ctx context.Context, id int64) (*models.ServiceModel, error) {
+	return s.getOne(ctx, s.selectBuilder().Where(squirrel.Eq{"s.id": id}))
+}
+
+func (s *ServiceRepo) GetByName(ctx context.Context, name string) (*models.ServiceModel, error) {
+	return s.getOne(ctx, s.selectBuilder().Where(
+		"s.id = (SELECT service_id FROM service_names WHERE lookup_key = ?)", models.NormalizeServiceName(name)))
+}
+
+func (s *ServiceRepo) List(ctx context.Context, filters *models.ServiceFilter) ([]*models.ServiceModel, error) {
+	builder := s.selectBuilder()
+	if filters.Category != "" {
+		builder = builder.Where(squirrel.Eq{"s.category": filters.Category})
+	}
+	if filters.Name != "" {
+		builder = builder.Where(
+			"s.id IN (SELECT service_id FROM service_names WHERE lookup_key = ?)", models.NormalizeServiceName(filters.Name))
+	}
+	return s.query(ctx, builder)
+}
+
+// Update writes the service and replaces its names. Subscriptions to the service are renamed
+// along with it, in the same transaction, like any other change of a subscription.
+func (s *ServiceRepo) Update(ctx context.Context, service *models.ServiceModel) error {
+	return withTx(ctx, s.DB, func(tx *sql.Tx) error {
+		query := `
+            UPDATE services
+            SET name = $2, category = $3, default_price = $4, currency = $5
+            WHERE id = $1`
+
+		res, err := tx.ExecContext(ctx, query, service.ID, service.Name, service.Category, service.DefaultPrice, service.Currency)
+		if err != nil {
+			return fmt.Errorf("failed to update service in database: %w", err)
+		}
+		if rowsAffected, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to get rows affected for service update: %w", err)
+		} else if rowsAffected == 0 {
+			return merrors.NewNotFoundErr("service not found")
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM service_names WHERE service_id = $1`, service.ID); err != nil {
+			return fmt.Errorf("failed to delete service names: %w", err)
+		}
+		if err := insertServiceNames(ctx, tx, service); err != nil {
+			return err
+		}
+
+		return renameSubscriptions(ctx, tx, service.ID, service.Name)
+	})
+}
+
+// Delete removes the service. Services that still have subscriptions cannot be deleted.
+func (s *ServiceRepo) Delete(ctx context.Context, id int64) error {
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM services WHERE id = $1`, id)
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return merrors.NewConflictError("service has subscriptions")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete service from database: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected for service deletion: %w", err)
+	} else if rowsAffected == 0 {
+		return merrors.NewNotFoundErr("service not found")
+	}
+
+	return nil
+}
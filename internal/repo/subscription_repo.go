@@ -1,16 +1,155 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
 	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
 )
 
+// SubscriptionRepository is the storage used by the subscription service.
+type SubscriptionRepository interface {
+	Create(ctx context.Context, subscription *models.SubscriptionModel) error
+	GetByFilters(ctx context.Context, filters *models.SubscriptionFilter) (*models.SubscriptionPage, error)
+	// Export calls fn for every matching subscription, in the filter sort order, regardless of the
+	// filter limit and cursor. It stops at the first error fn returns. The subscription passed to
+	// fn is only valid until fn returns.
+	Export(ctx context.Context, filters *models.SubscriptionFilter, fn func(*models.SubscriptionModel) error) error
+	GetByID(ctx context.Context, ID int64) (*models.SubscriptionModel, error)
+	// Update, Delete and Restore fail with a PreconditionFailedError unless the stored version
+	// matches: subscription.Version for Update, and version for Delete and Restore unless it is zero.
+	Update(ctx context.Context, subscription *models.SubscriptionModel) error
+	// Delete soft-deletes the subscription; Restore undoes it and Purge removes soft-deleted
+	// subscriptions for good.
+	Delete(ctx context.Context, id int64, version int64) error
+	Restore(ctx context.Context, id int64, version int64) (*models.SubscriptionModel, error)
+	// CreateBatch, UpdateBatch and DeleteBatch apply their items in one transaction and return an
+	// error per item. In atomic mode the batch is applied all or nothing: the first failure rolls
+	// everything back and the other items fail as aborted (see models.AbortBatch). Otherwise only
+	// the failed items are left out. versions may be nil.
+	CreateBatch(ctx context.Context, subscriptions []*models.SubscriptionModel, atomic bool) ([]error, error)
+	UpdateBatch(ctx context.Context, subscriptions []*models.SubscriptionModel, atomic bool) ([]error, error)
+	DeleteBatch(ctx context.Context, ids []int64, versions []int64, atomic bool) ([]error, error)
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetSum(ctx context.Context, filters *models.SubscriptionFilter) (models.Amounts, error)
+	GetMonthlySums(ctx context.Context, filters *models.SubscriptionFilter) ([]*models.MonthlySum, error)
+	GetHistory(ctx context.Context, id int64) ([]*models.SubscriptionEvent, error)
+}
+
+var _ SubscriptionRepository = (*SubscriptionRepo)(nil)
+
+// subscriptionColumns are the columns scanned by scanSubscription, in order.
+const subscriptionColumns = "id, user_id, price, currency, billing_period, start_date, end_date, service_id, service_name, deleted_at, version, " +
+	"trial_end, intro_price, intro_until"
+
+func scanSubscription(row interface{ Scan(dest ...any) error }, subscription *models.SubscriptionModel, extra ...any) error {
+	return row.Scan(append([]any{
+		&subscription.ID, &subscription.UserID, &subscription.Price, &subscription.Currency, &subscription.BillingPeriod,
+		&subscription.StartDate, &subscription.EndDate, &subscription.ServiceID, &subscription.ServiceName, &subscription.DeletedAt, &subscription.Version,
+		&subscription.TrialEnd, &subscription.IntroPrice, &subscription.IntroUntil,
+	}, extra...)...)
+}
+
+// subscriptionPricesColumn aggregates the price history of a subscriptions row into JSON.
+const subscriptionPricesColumn = `COALESCE((
+        SELECT json_agg(json_build_object('effective_from', p.effective_from, 'price', p.price) ORDER BY p.effective_from)
+        FROM subscription_prices p
+        WHERE p.subscription_id = subscriptions.id), '[]')`
+
+// subscriptionPausesColumn aggregates the pauses of a subscriptions row into JSON.
+const subscriptionPausesColumn = `COALESCE((
+        SELECT json_agg(json_build_object('from', p.paused_from, 'until', p.paused_until) ORDER BY p.paused_from)
+        FROM subscription_pauses p
+        WHERE p.subscription_id = subscriptions.id), '[]')`
+
+// subscriptionDetailColumns are the columns scanned by scanSubscriptionDetails, in order.
+const subscriptionDetailColumns = subscriptionColumns + ", " + subscriptionPricesColumn + ", " + subscriptionPausesColumn
+
+// scanSubscriptionDetails scans a subscription along with its price history and pauses.
+func scanSubscriptionDetails(row interface{ Scan(dest ...any) error }, subscription *models.SubscriptionModel) error {
+	var prices, pauses []byte
+	if err := scanSubscription(row, subscription, &prices, &pauses); err != nil {
+		return err
+	}
+
+	var priceRows []struct {
+		EffectiveFrom string `json:"effective_from"`
+		Price         int64  `json:"price"`
+	}
+	if err := json.Unmarshal(prices, &priceRows); err != nil {
+		return fmt.Errorf("failed to decode prices of subscription %d: %w", subscription.ID, err)
+	}
+	subscription.Prices = make([]models.SubscriptionPrice, len(priceRows))
+	for i, row := range priceRows {
+		effectiveFrom, err := time.Parse(models.DateFormat, row.EffectiveFrom)
+		if err != nil {
+			return fmt.Errorf("failed to decode prices of subscription %d: %w", subscription.ID, err)
+		}
+		subscription.Prices[i] = models.SubscriptionPrice{EffectiveFrom: effectiveFrom, Price: models.Money(row.Price)}
+	}
+
+	var pauseRows []struct {
+		From  string  `json:"from"`
+		Until *string `json:"until"`
+	}
+	if err := json.Unmarshal(pauses, &pauseRows); err != nil {
+		return fmt.Errorf("failed to decode pauses of subscription %d: %w", subscription.ID, err)
+	}
+	subscription.Pauses = nil
+	for _, row := range pauseRows {
+		var pause models.SubscriptionPause
+		var err error
+		if pause.From, err = time.Parse(models.DateFormat, row.From); err != nil {
+			return fmt.Errorf("failed to decode pauses of subscription %d: %w", subscription.ID, err)
+		}
+		if row.Until != nil {
+			until, err := time.Parse(models.DateFormat, *row.Until)
+			if err != nil {
+				return fmt.Errorf("failed to decode pauses of subscription %d: %w", subscription.ID, err)
+			}
+			pause.Until = &until
+		}
+		subscription.Pauses = append(subscription.Pauses, pause)
+	}
+	return nil
+}
+
+// writeDetails replaces the stored price history and pauses of the subscription with its
+// PriceHistory and Pauses.
+func writeDetails(ctx context.Context, tx *sql.Tx, subscription *models.SubscriptionModel) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM subscription_prices WHERE subscription_id = $1`, subscription.ID); err != nil {
+		return fmt.Errorf("failed to delete prices of subscription %d: %w", subscription.ID, err)
+	}
+	query := `
+        INSERT INTO subscription_prices (subscription_id, effective_from, price)
+        VALUES ($1, $2, $3)`
+	for _, price := range subscription.PriceHistory() {
+		if _, err := tx.ExecContext(ctx, query, subscription.ID, price.EffectiveFrom, price.Price); err != nil {
+			return fmt.Errorf("failed to store prices of subscription %d: %w", subscription.ID, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM subscription_pauses WHERE subscription_id = $1`, subscription.ID); err != nil {
+		return fmt.Errorf("failed to delete pauses of subscription %d: %w", subscription.ID, err)
+	}
+	query = `
+        INSERT INTO subscription_pauses (subscription_id, paused_from, paused_until)
+        VALUES ($1, $2, $3)`
+	for _, pause := range subscription.Pauses {
+		if _, err := tx.ExecContext(ctx, query, subscription.ID, pause.From, pause.Until); err != nil {
+			return fmt.Errorf("failed to store pauses of subscription %d: %w", subscription.ID, err)
+		}
+	}
+	return nil
+}
+
 type SubscriptionRepo struct {
 	DB *sql.DB
 }
@@ -19,35 +158,55 @@ func NewSubscriptionRepo(db *sql.DB) *SubscriptionRepo {
 	return &SubscriptionRepo{DB: db}
 }
 
-func (s *SubscriptionRepo) Create(subscription *models.SubscriptionModel) error {
-	query := `
-        INSERT INTO subscriptions (user_id, price, start_date, end_date, service_name)
-        VALUES ($1, $2, $3, $4, $5)`
+// Create inserts the subscription and fills it with the stored row, including the generated id.
+// The creation is recorded in the audit log in the same transaction.
+func (s *SubscriptionRepo) Create(ctx context.Context, subscription *models.SubscriptionModel) error {
+	return withTx(ctx, s.DB, func(tx *sql.Tx) error {
+		return createSubscription(ctx, tx, subscription)
+	})
+}
 
-	_, err := s.DB.Exec(query, subscription.UserID,
-		subscription.Price, subscription.StartDate, subscription.EndDate, subscription.ServiceName)
+func (s *SubscriptionRepo) CreateBatch(ctx context.Context, subscriptions []*models.SubscriptionModel, atomic bool) ([]error, error) {
+	return batchTx(ctx, s.DB, len(subscriptions), atomic, func(tx *sql.Tx, i int) error {
+		return createSubscription(ctx, tx, subscriptions[i])
+	})
+}
 
+// createSubscription inserts the subscription, fills it with the stored row and records its creation.
+func createSubscription(ctx context.Context, tx *sql.Tx, subscription *models.SubscriptionModel) error {
+	query := `
+        INSERT INTO subscriptions (user_id, price, currency, billing_period, start_date, end_date, service_id, service_name,
+            trial_end, intro_price, intro_until)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, (SELECT name FROM services WHERE id = $7), $8, $9, $10)
+        RETURNING ` + subscriptionColumns
+
+	err := scanSubscription(tx.QueryRowContext(ctx, query, subscription.UserID, subscription.Price, subscription.Currency,
+		subscription.BillingPeriod, subscription.StartDate, subscription.EndDate, subscription.ServiceID,
+		subscription.TrialEnd, subscription.IntroPrice, subscription.IntroUntil), subscription)
 	if err != nil {
 		return fmt.Errorf("failed to create subscription in database: %w", err)
 	}
+	if err := writeDetails(ctx, tx, subscription); err != nil {
+		return err
+	}
 
-	return nil
+	return insertEvent(ctx, tx, models.NewSubscriptionEvent(ctx, models.ActionCreated, nil, subscription))
 }
 
-func (s *SubscriptionRepo) GetByFilters(filters *models.SubscriptionFilter) ([]*models.SubscriptionModel, error) {
-	builder := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).
-		Select("id, user_id, price, start_date, end_date, service_name").
+func (s *SubscriptionRepo) selectBuilder() squirrel.SelectBuilder {
+	return squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).
+		Select(subscriptionDetailColumns).
 		From("subscriptions")
+}
 
-	builder = filters.ToSQL(builder)
-
+func (s *SubscriptionRepo) query(ctx context.Context, builder squirrel.SelectBuilder) ([]*models.SubscriptionModel, error) {
 	query, args, err := builder.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("failed to build SQL query for subscriptions: %w", err)
 	}
-	slog.Debug("GetByFilters query", "query", query, "args", args)
+	slog.Debug("Subscriptions query", "query", query, "args", args)
 
-	rows, err := s.DB.Query(query, args...)
+	rows, err := s.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute subscriptions query: %w", err)
 	}
@@ -56,9 +215,7 @@ func (s *SubscriptionRepo) GetByFilters(filters *models.SubscriptionFilter) ([]*
 	var subscriptions []*models.SubscriptionModel
 	for rows.Next() {
 		subscription := &models.SubscriptionModel{}
-		err := rows.Scan(
-			&subscription.ID, &subscription.UserID, &subscription.Price,
-			&subscription.StartDate, &subscription.EndDate, &subscription.ServiceName)
+		err := scanSubscriptionDetails(rows, subscription)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan subscription row: %w", err)
 		}
@@ -72,16 +229,87 @@ func (s *SubscriptionRepo) GetByFilters(filters *models.SubscriptionFilter) ([]*
 	return subscriptions, nil
 }
 
-func (s *SubscriptionRepo) GetByID(ID int64) (*models.SubscriptionModel, error) {
+// Export streams the rows of the query to fn as they are read, without holding them in memory.
+func (s *SubscriptionRepo) Export(ctx context.Context, filters *models.SubscriptionFilter, fn func(*models.SubscriptionModel) error) error {
+	query, args, err := filters.OrderToSQL(filters.ToSQL(s.selectBuilder())).ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build SQL query for subscription export: %w", err)
+	}
+	slog.Debug("Subscriptions export query", "query", query, "args", args)
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute subscription export query: %w", err)
+	}
+	defer rows.Close()
+
+	subscription := &models.SubscriptionModel{}
+	for rows.Next() {
+		*subscription = models.SubscriptionModel{}
+		if err := scanSubscriptionDetails(rows, subscription); err != nil {
+			return fmt.Errorf("failed to scan subscription row: %w", err)
+		}
+		if err := fn(subscription); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating subscription rows: %w", err)
+	}
+
+	return nil
+}
+
+// GetByFilters returns a single page of the matching subscriptions, ordered and limited as
+// requested by the filter, along with the total number of matches.
+func (s *SubscriptionRepo) GetByFilters(ctx context.Context, filters *models.SubscriptionFilter) (*models.SubscriptionPage, error) {
+	builder, err := filters.PageToSQL(filters.ToSQL(s.selectBuilder()))
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := s.query(ctx, builder)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.CountByFilters(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.NewSubscriptionPage(filters, subscriptions, total)
+}
+
+func (s *SubscriptionRepo) CountByFilters(ctx context.Context, filters *models.SubscriptionFilter) (int64, error) {
+	builder := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).
+		Select("COUNT(*)").
+		From("subscriptions")
+
+	builder = filters.ToSQL(builder)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build SQL query for subscription count: %w", err)
+	}
+
+	var count int64
+	if err := s.DB.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to execute subscription count query: %w", err)
+	}
+
+	return count, nil
+}
+
+func (s *SubscriptionRepo) GetByID(ctx context.Context, ID int64) (*models.SubscriptionModel, error) {
 	subscription := &models.SubscriptionModel{}
 	query := `
-        SELECT id, user_id, price, start_date, end_date, service_name
+        SELECT ` + subscriptionDetailColumns + `
         FROM subscriptions
-        WHERE id = $1`
+        WHERE id = $1 AND deleted_at IS NULL`
 
-	err := s.DB.QueryRow(query, ID).Scan(
-		&subscription.ID, &subscription.UserID, &subscription.Price,
-		&subscription.StartDate, &subscription.EndDate, &subscription.ServiceName)
+	err := scanSubscriptionDetails(s.DB.QueryRowContext(ctx, query, ID), subscription)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, merrors.NewNotFoundErr("subscription not found")
@@ -92,65 +320,230 @@ func (s *SubscriptionRepo) GetByID(ID int64) (*models.SubscriptionModel, error)
 	return subscription, nil
 }
 
-func (s *SubscriptionRepo) Update(subscription *models.SubscriptionModel) error {
+// lockForChange locks the live subscription for the rest of tx and returns it, checking that it is
+// still at version unless version is zero.
+func lockForChange(ctx context.Context, tx *sql.Tx, id, version int64) (*models.SubscriptionModel, error) {
+	subscription := &models.SubscriptionModel{}
+	query := `
+        SELECT ` + subscriptionDetailColumns + `
+        FROM subscriptions
+        WHERE id = $1 AND deleted_at IS NULL
+        FOR UPDATE`
+
+	if err := scanSubscriptionDetails(tx.QueryRowContext(ctx, query, id), subscription); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, merrors.NewNotFoundErr("subscription not found")
+		}
+		return nil, fmt.Errorf("failed to lock subscription %d: %w", id, err)
+	}
+
+	if version != 0 && subscription.Version != version {
+		return nil, merrors.NewPreconditionFailedError(fmt.Sprintf("subscription is at version %d, not %d", subscription.Version, version))
+	}
+	return subscription, nil
+}
+
+// Update writes every column of the subscription, bumps its version and fills it with the stored
+// row. The change is recorded in the audit log, with the row as it was before, in the same transaction.
+func (s *SubscriptionRepo) Update(ctx context.Context, subscription *models.SubscriptionModel) error {
+	return withTx(ctx, s.DB, func(tx *sql.Tx) error {
+		return updateSubscription(ctx, tx, subscription)
+	})
+}
+
+func (s *SubscriptionRepo) UpdateBatch(ctx context.Context, subscriptions []*models.SubscriptionModel, atomic bool) ([]error, error) {
+	return batchTx(ctx, s.DB, len(subscriptions), atomic, func(tx *sql.Tx, i int) error {
+		return updateSubscription(ctx, tx, subscriptions[i])
+	})
+}
+
+func updateSubscription(ctx context.Context, tx *sql.Tx, subscription *models.SubscriptionModel) error {
+	before, err := lockForChange(ctx, tx, subscription.ID, subscription.Version)
+	if err != nil {
+		return err
+	}
+
 	query := `
         UPDATE subscriptions
-        SET price = $2, start_date = $3, end_date = $4, user_id = $5, service_name = $6
-        WHERE id = $1`
+        SET price = $2, start_date = $3, end_date = $4, user_id = $5, currency = $6, billing_period = $7,
+            service_id = $8, service_name = (SELECT name FROM services WHERE id = $8),
+            trial_end = $9, intro_price = $10, intro_until = $11, version = version + 1
+        WHERE id = $1
+        RETURNING ` + subscriptionColumns
 
-	res, err := s.DB.Exec(query, subscription.ID, subscription.Price,
+	err = scanSubscription(tx.QueryRowContext(ctx, query, subscription.ID, subscription.Price,
 		subscription.StartDate, subscription.EndDate, subscription.UserID,
-		subscription.ServiceName)
+		subscription.Currency, subscription.BillingPeriod, subscription.ServiceID,
+		subscription.TrialEnd, subscription.IntroPrice, subscription.IntroUntil), subscription)
 	if err != nil {
 		return fmt.Errorf("failed to update subscription in database: %w", err)
 	}
+	if err := writeDetails(ctx, tx, subscription); err != nil {
+		return err
+	}
+
+	return insertEvent(ctx, tx, models.NewSubscriptionEvent(ctx, models.ActionUpdated, before, subscription))
+}
 
-	rowsAffected, err := res.RowsAffected()
+// renameSubscriptions gives the subscriptions of the service, deleted ones included, the service's
+// new name within tx. Each renamed subscription gets a new version and an updated event.
+func renameSubscriptions(ctx context.Context, tx *sql.Tx, serviceID int64, name string) error {
+	query := `
+        SELECT ` + subscriptionDetailColumns + `
+        FROM subscriptions
+        WHERE service_id = $1 AND service_name <> $2
+        ORDER BY id
+        FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, query, serviceID, name)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected for subscription update: %w", err)
-	} else if rowsAffected == 0 {
-		return merrors.NewNotFoundErr("subscription not found")
+		return fmt.Errorf("failed to lock subscriptions of service %d: %w", serviceID, err)
+	}
+	var renamed []*models.SubscriptionModel
+	for rows.Next() {
+		subscription := &models.SubscriptionModel{}
+		if err := scanSubscriptionDetails(rows, subscription); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan subscription row: %w", err)
+		}
+		renamed = append(renamed, subscription)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating subscription rows: %w", err)
 	}
 
+	for _, before := range renamed {
+		after := *before
+		after.ServiceName = name
+		err := tx.QueryRowContext(ctx, `UPDATE subscriptions SET service_name = $2, version = version + 1 WHERE id = $1 RETURNING version`,
+			before.ID, name).Scan(&after.Version)
+		if err != nil {
+			return fmt.Errorf("failed to rename subscription %d: %w", before.ID, err)
+		}
+		if err := insertEvent(ctx, tx, models.NewSubscriptionEvent(ctx, models.ActionUpdated, before, &after)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (s *SubscriptionRepo) Delete(id int64) error {
-	query := `DELETE FROM subscriptions WHERE id = $1`
-	res, err := s.DB.Exec(query, id)
+// Delete soft-deletes the subscription by setting deleted_at, and records its last state in the
+// audit log in the same transaction.
+func (s *SubscriptionRepo) Delete(ctx context.Context, id int64, version int64) error {
+	return withTx(ctx, s.DB, func(tx *sql.Tx) error {
+		return deleteSubscription(ctx, tx, id, version)
+	})
+}
+
+func (s *SubscriptionRepo) DeleteBatch(ctx context.Context, ids []int64, versions []int64, atomic bool) ([]error, error) {
+	return batchTx(ctx, s.DB, len(ids), atomic, func(tx *sql.Tx, i int) error {
+		var version int64
+		if versions != nil {
+			version = versions[i]
+		}
+		return deleteSubscription(ctx, tx, ids[i], version)
+	})
+}
+
+func deleteSubscription(ctx context.Context, tx *sql.Tx, id int64, version int64) error {
+	before, err := lockForChange(ctx, tx, id, version)
 	if err != nil {
-		return fmt.Errorf("failed to delete subscription from database: %w", err)
+		return err
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	query := `
+        UPDATE subscriptions
+        SET deleted_at = now(), version = version + 1
+        WHERE id = $1`
+
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to delete subscription in database: %w", err)
+	}
+
+	return insertEvent(ctx, tx, models.NewSubscriptionEvent(ctx, models.ActionDeleted, before, nil))
+}
+
+// Restore undoes a soft delete and returns the restored subscription.
+func (s *SubscriptionRepo) Restore(ctx context.Context, id int64, version int64) (*models.SubscriptionModel, error) {
+	subscription := &models.SubscriptionModel{}
+	err := withTx(ctx, s.DB, func(tx *sql.Tx) error {
+		var current int64
+		err := tx.QueryRowContext(ctx, `SELECT version FROM subscriptions WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE`, id).Scan(&current)
+		if errors.Is(err, sql.ErrNoRows) {
+			return merrors.NewNotFoundErr("deleted subscription not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock subscription %d: %w", id, err)
+		}
+		if version != 0 && current != version {
+			return merrors.NewPreconditionFailedError(fmt.Sprintf("subscription is at version %d, not %d", current, version))
+		}
+
+		query := `
+            UPDATE subscriptions
+            SET deleted_at = NULL, version = version + 1
+            WHERE id = $1
+            RETURNING ` + subscriptionDetailColumns
+
+		if err := scanSubscriptionDetails(tx.QueryRowContext(ctx, query, id), subscription); err != nil {
+			return fmt.Errorf("failed to restore subscription in database: %w", err)
+		}
+
+		return insertEvent(ctx, tx, models.NewSubscriptionEvent(ctx, models.ActionRestored, nil, subscription))
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected for subscription deletion: %w", err)
-	} else if rowsAffected == 0 {
-		return merrors.NewNotFoundErr("subscription not found")
+		return nil, err
 	}
 
-	return nil
+	return subscription, nil
 }
 
-func (s *SubscriptionRepo) GetSum(filters *models.SubscriptionFilter) (float64, error) {
-	var sum float64
+// Purge permanently removes the subscriptions soft-deleted before deletedBefore and returns how
+// many were removed. Their audit logs are kept and end with a purged event.
+func (s *SubscriptionRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	query := `
+        WITH purged AS (
+            DELETE FROM subscriptions
+            WHERE deleted_at < $1
+            RETURNING id
+        )
+        INSERT INTO subscription_events (subscription_id, action, actor)
+        SELECT id, $2, $3 FROM purged`
 
-	builder := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).
-		Select("COALESCE(SUM(price), 0)").
-		From("subscriptions")
+	res, err := s.DB.ExecContext(ctx, query, deletedBefore, models.ActionPurged, models.ActorFromContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted subscriptions: %w", err)
+	}
 
-	builder = filters.ToSQL(builder)
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected for subscription purge: %w", err)
+	}
 
-	query, args, err := builder.ToSql()
+	return purged, nil
+}
+
+// GetSum returns the total cost of the matching subscriptions over the filter period, per currency:
+// each subscription's price charged on every billing date that falls inside [From, Till].
+// filters.Till must be set.
+func (s *SubscriptionRepo) GetSum(ctx context.Context, filters *models.SubscriptionFilter) (models.Amounts, error) {
+	subscriptions, err := s.query(ctx, filters.ToSQL(s.selectBuilder()))
 	if err != nil {
-		return 0, fmt.Errorf("failed to build SQL query for subscription sum: %w", err)
+		return nil, fmt.Errorf("failed to get subscriptions for sum: %w", err)
 	}
-	slog.Debug("GetByFilters query", "query", query, "args", args)
 
-	err = s.DB.QueryRow(query, args...).Scan(&sum)
+	return models.TotalCost(ctx, subscriptions, filters.From, filters.Till)
+}
+
+// GetMonthlySums returns the unconverted spending of the matching subscriptions for every month in
+// [From, Till]. When From is not set, the series starts at the earliest matching subscription.
+// filters.Till must be set.
+func (s *SubscriptionRepo) GetMonthlySums(ctx context.Context, filters *models.SubscriptionFilter) ([]*models.MonthlySum, error) {
+	subscriptions, err := s.query(ctx, filters.ToSQL(s.selectBuilder()))
 	if err != nil {
-		return 0, fmt.Errorf("failed to execute subscription sum query: %w", err)
+		return nil, fmt.Errorf("failed to get subscriptions for monthly sums: %w", err)
 	}
 
-	return sum, nil
+	return models.NewMonthlySums(ctx, subscriptions, filters.From, filters.Till)
 }
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+)
+
+// withTx runs fn in a transaction that is committed if fn succeeds and rolled back otherwise.
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// errBatchAborted rolls back the transaction of an atomic batch after an item failed.
+var errBatchAborted = errors.New("batch aborted")
+
+// batchTx applies n items with apply in one transaction and returns an error per item. In atomic
+// mode the first failure rolls back the transaction and aborts the other items. Otherwise every
+// item runs in a savepoint, so that a failed item is rolled back alone and the rest are committed.
+// Errors outside of apply, and a cancelled ctx, fail the whole batch.
+func batchTx(ctx context.Context, db *sql.DB, n int, atomic bool, apply func(tx *sql.Tx, i int) error) ([]error, error) {
+	errs := make([]error, n)
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		for i := range n {
+			if !atomic {
+				if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_item"); err != nil {
+					return fmt.Errorf("failed to create savepoint for batch item %d: %w", i, err)
+				}
+			}
+
+			err := apply(tx, i)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+
+			switch {
+			case err != nil && atomic:
+				errs[i] = err
+				models.AbortBatch(errs)
+				return errBatchAborted
+			case err != nil:
+				errs[i] = err
+				if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_item"); err != nil {
+					return fmt.Errorf("failed to roll back batch item %d: %w", i, err)
+				}
+			case !atomic:
+				if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT batch_item"); err != nil {
+					return fmt.Errorf("failed to release savepoint for batch item %d: %w", i, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchAborted) {
+		return nil, err
+	}
+
+	return errs, nil
+}
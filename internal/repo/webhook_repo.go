@@ -0,0 +1,292 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+)
+
+// WebhookRepository stores webhooks and the outbox of deliveries to them. Deliveries are added by
+// the SubscriptionRepository as part of every change a webhook receives.
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *models.WebhookModel) error
+	GetByID(ctx context.Context, id int64) (*models.WebhookModel, error)
+	List(ctx context.Context) ([]*models.WebhookModel, error)
+	// Delete removes the webhook along with its deliveries.
+	Delete(ctx context.Context, id int64) error
+
+	// ListDeliveries returns the latest deliveries to the webhook, newest first, only those with
+	// the status unless it is empty.
+	ListDeliveries(ctx context.Context, webhookID int64, status string) ([]*models.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, webhookID, id int64) (*models.WebhookDelivery, error)
+	// ClaimDeliveries returns up to limit pending deliveries due at now, along with the URL and
+	// secret of their webhook, and postpones them by lease so that no other worker claims them
+	// while they are being sent.
+	ClaimDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*models.WebhookDelivery, error)
+	// SaveDelivery stores the status, attempts and schedule of the delivery.
+	SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	// RedeliverDelivery queues the delivery to be sent again at now with a fresh set of attempts,
+	// in a single step, and returns it. It fails with a ConflictError for a pending delivery that
+	// is not due yet, which may have been claimed and be in flight.
+	RedeliverDelivery(ctx context.Context, webhookID, id int64, now time.Time) (*models.WebhookDelivery, error)
+}
+
+// deliveriesLimit caps how many deliveries ListDeliveries returns.
+const deliveriesLimit = 100
+
+var _ WebhookRepository = (*WebhookRepo)(nil)
+
+// WebhookRepo stores webhooks in the webhooks table and their outbox in webhook_deliveries.
+type WebhookRepo struct {
+	DB *sql.DB
+}
+
+func NewWebhookRepo(db *sql.DB) *WebhookRepo {
+	return &WebhookRepo{DB: db}
+}
+
+// enqueueWebhooks adds a pending delivery of the event, which must have been inserted already, for
+// every webhook that receives it to the outbox within tx. The deliveries are thus sent if and only
+// if the change they announce is committed.
+func enqueueWebhooks(ctx context.Context, tx *sql.Tx, event *models.SubscriptionEvent) error {
+	if !(&models.WebhookModel{}).Receives(event.Action) {
+		return nil
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	query := `
+        INSERT INTO webhook_deliveries (webhook_id, event_id, action, payload)
+        SELECT id, $1, $2, $3
+        FROM webhooks
+        WHERE jsonb_array_length(events) = 0 OR events ? $2`
+
+	if _, err := tx.ExecContext(ctx, query, event.ID, event.Action, string(payload)); err != nil {
+		return fmt.Errorf("failed to enqueue webhook deliveries of subscription %s event: %w", event.Action, err)
+	}
+	return nil
+}
+
+const webhookColumns = "id, url, secret, events, created_at"
+
+func scanWebhook(row interface{ Scan(dest ...any) error }, webhook *models.WebhookModel) error {
+	var events []byte
+	if err := row.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &events, &webhook.CreatedAt); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(events, &webhook.Events); err != nil {
+		return fmt.Errorf("failed to decode webhook events: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepo) Create(ctx context.Context, webhook *models.WebhookModel) error {
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook events: %w", err)
+	}
+
+	query := `
+        INSERT INTO webhooks (url, secret, events)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at`
+
+	if err := r.DB.QueryRowContext(ctx, query, webhook.URL, webhook.Secret, string(events)).Scan(&webhook.ID, &webhook.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create webhook in database: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepo) GetByID(ctx context.Context, id int64) (*models.WebhookModel, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+
+	webhook := &models.WebhookModel{}
+	err := scanWebhook(r.DB.QueryRowContext(ctx, query, id), webhook)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, merrors.NewNotFoundErr("webhook not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook %d: %w", id, err)
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepo) List(ctx context.Context) ([]*models.WebhookModel, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks ORDER BY id`
+
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []*models.WebhookModel{}
+	for rows.Next() {
+		webhook := &models.WebhookModel{}
+		if err := scanWebhook(rows, webhook); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook row: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook rows: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepo) Delete(ctx context.Context, id int64) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook %d: %w", id, err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook %d: %w", id, err)
+	}
+	if deleted == 0 {
+		return merrors.NewNotFoundErr("webhook not found")
+	}
+	return nil
+}
+
+const deliveryColumns = "d.id, d.webhook_id, d.event_id, d.action, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_error, d.created_at, d.delivered_at"
+
+func deliveryFields(delivery *models.WebhookDelivery) []any {
+	return []any{&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.Action, (*[]byte)(&delivery.Payload), &delivery.Status,
+		&delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastError, &delivery.CreatedAt, &delivery.DeliveredAt}
+}
+
+// queryDeliveries runs a query selecting deliveryColumns followed by the columns that extra,
+// if set, returns where to scan for a delivery.
+func (r *WebhookRepo) queryDeliveries(ctx context.Context, query string, args []any, extra func(*models.WebhookDelivery) []any) ([]*models.WebhookDelivery, error) {
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery := &models.WebhookDelivery{}
+		fields := deliveryFields(delivery)
+		if extra != nil {
+			fields = append(fields, extra(delivery)...)
+		}
+		if err := rows.Scan(fields...); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery row: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook delivery rows: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, webhookID int64, status string) ([]*models.WebhookDelivery, error) {
+	if _, err := r.GetByID(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	query := `
+        SELECT ` + deliveryColumns + `
+        FROM webhook_deliveries d
+        WHERE d.webhook_id = $1 AND ($2::text = '' OR d.status = $2)
+        ORDER BY d.id DESC
+        LIMIT $3`
+
+	return r.queryDeliveries(ctx, query, []any{webhookID, status, deliveriesLimit}, nil)
+}
+
+func (r *WebhookRepo) GetDelivery(ctx context.Context, webhookID, id int64) (*models.WebhookDelivery, error) {
+	query := `
+        SELECT ` + deliveryColumns + `
+        FROM webhook_deliveries d
+        WHERE d.webhook_id = $1 AND d.id = $2`
+
+	delivery := &models.WebhookDelivery{}
+	err := r.DB.QueryRowContext(ctx, query, webhookID, id).Scan(deliveryFields(delivery)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, merrors.NewNotFoundErr("webhook delivery not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery %d: %w", id, err)
+	}
+	return delivery, nil
+}
+
+func (r *WebhookRepo) ClaimDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	query := `
+        WITH due AS (
+            SELECT id
+            FROM webhook_deliveries
+            WHERE status = 'pending' AND next_attempt_at <= $1
+            ORDER BY next_attempt_at, id
+            LIMIT $2
+            FOR UPDATE SKIP LOCKED
+        )
+        UPDATE webhook_deliveries d
+        SET next_attempt_at = $3
+        FROM due, webhooks w
+        WHERE d.id = due.id AND w.id = d.webhook_id
+        RETURNING ` + deliveryColumns + `, w.url, w.secret`
+
+	deliveries, err := r.queryDeliveries(ctx, query, []any{now, limit, now.Add(lease)}, func(delivery *models.WebhookDelivery) []any {
+		return []any{&delivery.URL, &delivery.Secret}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepo) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+        UPDATE webhook_deliveries
+        SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, delivered_at = $6
+        WHERE id = $1`
+
+	res, err := r.DB.ExecContext(ctx, query, delivery.ID, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastError, delivery.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("failed to save webhook delivery %d: %w", delivery.ID, err)
+	}
+	saved, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save webhook delivery %d: %w", delivery.ID, err)
+	}
+	if saved == 0 {
+		return merrors.NewNotFoundErr("webhook delivery not found")
+	}
+	return nil
+}
+
+func (r *WebhookRepo) RedeliverDelivery(ctx context.Context, webhookID, id int64, now time.Time) (*models.WebhookDelivery, error) {
+	query := `
+        UPDATE webhook_deliveries d
+        SET status = 'pending', attempts = 0, next_attempt_at = $3, last_error = '', delivered_at = NULL
+        WHERE d.webhook_id = $1 AND d.id = $2 AND NOT (d.status = 'pending' AND d.next_attempt_at > $3)
+        RETURNING ` + deliveryColumns
+
+	delivery := &models.WebhookDelivery{}
+	err := r.DB.QueryRowContext(ctx, query, webhookID, id, now).Scan(deliveryFields(delivery)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := r.GetDelivery(ctx, webhookID, id); err != nil {
+			return nil, err
+		}
+		return nil, merrors.NewConflictError("webhook delivery is pending and not due yet, it may be being sent")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeliver webhook delivery %d: %w", id, err)
+	}
+	return delivery, nil
+}
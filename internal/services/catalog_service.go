@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/repo"
+)
+
+// CatalogService manages the service catalog and resolves the service references of subscriptions.
+type CatalogService struct {
+	serviceRepo repo.ServiceRepository
+}
+
+func NewCatalogService(serviceRepo repo.ServiceRepository) *CatalogService {
+	return &CatalogService{serviceRepo: serviceRepo}
+}
+
+func (s *CatalogService) Create(ctx context.Context, req *models.ServiceCreateReq) (*models.ServiceModel, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("service creation validation failed: %w", err)
+	}
+
+	service := req.ToModel()
+	if err := s.serviceRepo.Create(ctx, service); err != nil {
+		return nil, err
+	}
+
+	return service, nil
+}
+
+func (s *CatalogService) GetByID(ctx context.Context, id int64) (*models.ServiceModel, error) {
+	service, err := s.serviceRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service by ID %d: %w", id, err)
+	}
+
+	return service, nil
+}
+
+func (s *CatalogService) List(ctx context.Context, filter *models.ServiceFilter) ([]*models.ServiceModel, error) {
+	services, err := s.serviceRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+
+	return services, nil
+}
+
+func (s *CatalogService) Update(ctx context.Context, id int64, req *models.ServiceUpdateReq) (*models.ServiceModel, error) {
+	service, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing service for update: %w", err)
+	}
+
+	req.PatchModel(service)
+	if err := service.Validate(); err != nil {
+		return nil, fmt.Errorf("patched service validation failed: %w", err)
+	}
+
+	if err := s.serviceRepo.Update(ctx, service); err != nil {
+		return nil, err
+	}
+
+	return service, nil
+}
+
+func (s *CatalogService) Delete(ctx context.Context, id int64) error {
+	if err := s.serviceRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete service with ID %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// Resolve returns the service a subscription refers to: the one with the given id if it is set,
+// otherwise the one known by name. A name that is not in the catalog yet is added to it.
+func (s *CatalogService) Resolve(ctx context.Context, id int64, name string) (*models.ServiceModel, error) {
+	service, err := s.Find(ctx, id, name)
+	if err != nil || service.ID != 0 {
+		return service, err
+	}
+
+	err = s.serviceRepo.Create(ctx, service)
+	var conflict *merrors.ConflictError
+	if errors.As(err, &conflict) {
+		// Someone else added the same name in the meantime.
+		return s.serviceRepo.GetByName(ctx, service.Name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to add service %q to the catalog: %w", service.Name, err)
+	}
+
+	return service, nil
+}
+
+// Find is Resolve without changing the catalog: for a name that is not in the catalog yet it
+// returns the entry Resolve would add, without an id.
+func (s *CatalogService) Find(ctx context.Context, id int64, name string) (*models.ServiceModel, error) {
+	if id != 0 {
+		service, err := s.serviceRepo.GetByID(ctx, id)
+		var notFound *merrors.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, merrors.NewValidationError(fmt.Sprintf("unknown service_id %d", id))
+		}
+		return service, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, merrors.NewValidationError("service_id or service_name is required")
+	}
+
+	service, err := s.Lookup(ctx, name)
+	if err != nil || service != nil {
+		return service, err
+	}
+
+	return &models.ServiceModel{Name: name, Aliases: []string{}, Currency: models.DefaultCurrency}, nil
+}
+
+// Lookup returns the service known by name, or nil if there is none.
+func (s *CatalogService) Lookup(ctx context.Context, name string) (*models.ServiceModel, error) {
+	service, err := s.serviceRepo.GetByName(ctx, name)
+	var notFound *merrors.NotFoundError
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up service %q: %w", name, err)
+	}
+
+	return service, nil
+}
@@ -1,57 +1,209 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
+	"time"
 
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/fx"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
 	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
 	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/repo"
 )
 
 type SubscriptionService struct {
-	subscriptionRepo *repo.SubscriptionRepo
+	subscriptionRepo repo.SubscriptionRepository
+	catalog          *CatalogService
+	rates            fx.RateProvider
+	baseCurrency     string
 }
 
-func NewSubscriptionService(subscriptionRepo *repo.SubscriptionRepo) *SubscriptionService {
-	return &SubscriptionService{subscriptionRepo: subscriptionRepo}
+// NewSubscriptionService creates the service. Service names are resolved through catalog.
+// Aggregates are converted with rates into the requested currency, or into baseCurrency when
+// none is requested.
+func NewSubscriptionService(subscriptionRepo repo.SubscriptionRepository, catalog *CatalogService, rates fx.RateProvider, baseCurrency string) *SubscriptionService {
+	return &SubscriptionService{
+		subscriptionRepo: subscriptionRepo,
+		catalog:          catalog,
+		rates:            rates,
+		baseCurrency:     baseCurrency,
+	}
+}
+
+func (s *SubscriptionService) converter(ctx context.Context) models.ConvertFunc {
+	return func(amount models.Money, from, to string) (models.Money, error) {
+		return fx.Convert(ctx, s.rates, amount, from, to)
+	}
+}
+
+func (s *SubscriptionService) targetCurrency(filter *models.SubscriptionFilter) string {
+	if filter.Currency != "" {
+		return filter.Currency
+	}
+	return s.baseCurrency
+}
+
+// resolveFilter returns a copy of the filter with a service name replaced by the id of the
+// catalog entry it refers to, so that aliases and differently spelled names match too.
+func (s *SubscriptionService) resolveFilter(ctx context.Context, filter *models.SubscriptionFilter) (*models.SubscriptionFilter, error) {
+	resolved := *filter
+	if filter.ServiceName == "" || filter.ServiceID != 0 {
+		return &resolved, nil
+	}
+
+	service, err := s.catalog.Lookup(ctx, filter.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	if service != nil {
+		resolved.ServiceID = service.ID
+		resolved.ServiceName = ""
+	}
+	return &resolved, nil
+}
+
+func (s *SubscriptionService) Create(ctx context.Context, subCreateReq *models.SubscriptionCreateReq) (*models.SubscriptionModel, error) {
+	sub, err := s.prepare(ctx, subCreateReq, s.catalog.Resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.subscriptionRepo.Create(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
 }
 
-func (s *SubscriptionService) Create(subCreateReq *models.SubscriptionCreateReq) error {
+// prepare validates the request and turns it into a subscription with its service resolved and
+// its price defaulted, ready to be stored. resolve is CatalogService.Resolve, or Find to leave
+// the catalog untouched.
+func (s *SubscriptionService) prepare(ctx context.Context, subCreateReq *models.SubscriptionCreateReq, resolve func(ctx context.Context, id int64, name string) (*models.ServiceModel, error)) (*models.SubscriptionModel, error) {
 	if err := subCreateReq.Validate(); err != nil {
-		return fmt.Errorf("subscription creation validation failed: %w", err)
+		return nil, fmt.Errorf("subscription creation validation failed: %w", err)
 	}
 
 	sub, err := subCreateReq.ToModel()
 	if err != nil {
-		return fmt.Errorf("failed to convert subscription request to model: %w", err)
+		return nil, fmt.Errorf("failed to convert subscription request to model: %w", err)
+	}
+
+	service, err := resolve(ctx, subCreateReq.ServiceID, subCreateReq.ServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve subscription service: %w", err)
+	}
+	sub.ServiceID, sub.ServiceName = service.ID, service.Name
+
+	if sub.Price == 0 {
+		if service.DefaultPrice == nil {
+			return nil, merrors.NewValidationError(fmt.Sprintf("price is required, service %q has no default price", service.Name))
+		}
+		sub.Price = *service.DefaultPrice
+		if subCreateReq.Currency == "" {
+			sub.Currency = service.Currency
+		}
+	}
+
+	return sub, nil
+}
+
+func (s *SubscriptionService) GetSum(ctx context.Context, filter *models.SubscriptionFilter) (*models.CostSummary, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, fmt.Errorf("subscription sum filter validation failed: %w", err)
+	}
+
+	period, err := s.resolveFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if period.Till.IsZero() {
+		period.Till = models.MonthEnd(time.Now())
+	}
+
+	subtotals, err := s.subscriptionRepo.GetSum(ctx, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription sum from repository: %w", err)
 	}
 
-	return s.subscriptionRepo.Create(sub)
+	summary := &models.CostSummary{
+		Currency:  s.targetCurrency(filter),
+		Subtotals: subtotals,
+	}
+	summary.Sum, err = subtotals.Convert(summary.Currency, s.converter(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert subscription sum to %s: %w", summary.Currency, err)
+	}
+
+	return summary, nil
 }
 
-func (s *SubscriptionService) GetSum(filter *models.SubscriptionFilter) (float64, error) {
+func (s *SubscriptionService) GetMonthlySums(ctx context.Context, filter *models.SubscriptionFilter) ([]*models.MonthlySum, error) {
 	if err := filter.Validate(); err != nil {
-		return 0, fmt.Errorf("subscription sum filter validation failed: %w", err)
+		return nil, fmt.Errorf("subscription monthly sums filter validation failed: %w", err)
+	}
+
+	period, err := s.resolveFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if period.Till.IsZero() {
+		period.Till = models.MonthEnd(time.Now())
 	}
 
-	sum, err := s.subscriptionRepo.GetSum(filter)
+	sums, err := s.subscriptionRepo.GetMonthlySums(ctx, period)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get subscription sum from repository: %w", err)
+		return nil, fmt.Errorf("failed to get subscription monthly sums from repository: %w", err)
+	}
+
+	currency := s.targetCurrency(filter)
+	convert := s.converter(ctx)
+	for _, sum := range sums {
+		if err := sum.Convert(currency, convert); err != nil {
+			return nil, fmt.Errorf("failed to convert subscription monthly sums to %s: %w", currency, err)
+		}
 	}
 
-	return sum, nil
+	return sums, nil
 }
 
-func (s *SubscriptionService) GetByFilters(filter *models.SubscriptionFilter) ([]*models.SubscriptionModel, error) {
-	subs, err := s.subscriptionRepo.GetByFilters(filter)
+func (s *SubscriptionService) GetByFilters(ctx context.Context, filter *models.SubscriptionFilter) (*models.SubscriptionPage, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, fmt.Errorf("subscription list filter validation failed: %w", err)
+	}
+
+	resolved, err := s.resolveFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.subscriptionRepo.GetByFilters(ctx, resolved)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get subscriptions by filters: %w", err)
 	}
 
-	return subs, nil
+	return page, nil
+}
+
+// Export calls fn for every subscription matching the filter, in its sort order but regardless
+// of its limit and cursor.
+func (s *SubscriptionService) Export(ctx context.Context, filter *models.SubscriptionFilter, fn func(*models.SubscriptionModel) error) error {
+	if err := filter.Validate(); err != nil {
+		return fmt.Errorf("subscription export filter validation failed: %w", err)
+	}
+
+	resolved, err := s.resolveFilter(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	return s.subscriptionRepo.Export(ctx, resolved, fn)
 }
 
-func (s *SubscriptionService) GetByID(ID int64) (*models.SubscriptionModel, error) {
-	sub, err := s.subscriptionRepo.GetByID(ID)
+func (s *SubscriptionService) GetByID(ctx context.Context, ID int64) (*models.SubscriptionModel, error) {
+	sub, err := s.subscriptionRepo.GetByID(ctx, ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get subscription by ID %d: %w", ID, err)
 	}
@@ -59,8 +211,31 @@ func (s *SubscriptionService) GetByID(ID int64) (*models.SubscriptionModel, erro
 	return sub, nil
 }
 
-func (s *SubscriptionService) Delete(ID int64) error {
-	err := s.subscriptionRepo.Delete(ID)
+// Restore undoes the soft delete of the subscription. A non-zero version makes the restoration
+// conditional on the subscription still being at that version.
+func (s *SubscriptionService) Restore(ctx context.Context, ID int64, version int64) (*models.SubscriptionModel, error) {
+	sub, err := s.subscriptionRepo.Restore(ctx, ID, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore subscription with ID %d: %w", ID, err)
+	}
+
+	return sub, nil
+}
+
+// GetHistory returns the audit log of the subscription, oldest first, including after it was deleted.
+func (s *SubscriptionService) GetHistory(ctx context.Context, ID int64) ([]*models.SubscriptionEvent, error) {
+	events, err := s.subscriptionRepo.GetHistory(ctx, ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history of subscription %d: %w", ID, err)
+	}
+
+	return events, nil
+}
+
+// Delete soft-deletes the subscription. A non-zero version makes the deletion conditional on the
+// subscription still being at that version.
+func (s *SubscriptionService) Delete(ctx context.Context, ID int64, version int64) error {
+	err := s.subscriptionRepo.Delete(ctx, ID, version)
 	if err != nil {
 		return fmt.Errorf("failed to delete subscription with ID %d: %w", ID, err)
 	}
@@ -68,20 +243,305 @@ func (s *SubscriptionService) Delete(ID int64) error {
 	return nil
 }
 
-func (s *SubscriptionService) Update(id int64, subUpdateReq *models.SubscriptionUpdateReq) error {
+// updateAttempts bounds how many times a change without a version re-reads and re-applies itself
+// after losing a race with a concurrent change.
+const updateAttempts = 3
+
+// Update applies the patch to the subscription. With a non-zero version the update fails with a
+// PreconditionFailedError unless the subscription is still at that version; without one, the patch
+// is retried on top of concurrent changes.
+func (s *SubscriptionService) Update(ctx context.Context, id int64, version int64, subUpdateReq *models.SubscriptionUpdateReq) (*models.SubscriptionModel, error) {
 	if err := subUpdateReq.Validate(); err != nil {
-		return fmt.Errorf("subscription update validation failed: %w", err)
+		return nil, fmt.Errorf("subscription update validation failed: %w", err)
+	}
+
+	return s.change(ctx, id, version, s.patch(ctx, subUpdateReq))
+}
+
+// Pause records a pause of the subscription. Like Update, it is conditional on a non-zero version.
+func (s *SubscriptionService) Pause(ctx context.Context, id int64, version int64, req *models.SubscriptionPauseReq) (*models.SubscriptionModel, error) {
+	sub, err := s.change(ctx, id, version, func(sub *models.SubscriptionModel) error {
+		return req.Apply(sub, models.Today())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to pause subscription with ID %d: %w", id, err)
+	}
+
+	return sub, nil
+}
+
+// Resume ends the pause of the subscription. Like Update, it is conditional on a non-zero version.
+func (s *SubscriptionService) Resume(ctx context.Context, id int64, version int64, req *models.SubscriptionResumeReq) (*models.SubscriptionModel, error) {
+	sub, err := s.change(ctx, id, version, func(sub *models.SubscriptionModel) error {
+		return req.Apply(sub, models.Today())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resume subscription with ID %d: %w", id, err)
+	}
+
+	return sub, nil
+}
+
+// change applies apply to the subscription and stores it, retrying on top of concurrent changes
+// when version is zero.
+func (s *SubscriptionService) change(ctx context.Context, id int64, version int64, apply func(*models.SubscriptionModel) error) (*models.SubscriptionModel, error) {
+	for attempt := 1; ; attempt++ {
+		sub, err := s.update(ctx, id, version, apply)
+		var precondition *merrors.PreconditionFailedError
+		if version == 0 && attempt < updateAttempts && errors.As(err, &precondition) {
+			continue
+		}
+		return sub, err
+	}
+}
+
+func (s *SubscriptionService) update(ctx context.Context, id int64, version int64, apply func(*models.SubscriptionModel) error) (*models.SubscriptionModel, error) {
+	sub, err := s.patched(ctx, id, version, apply)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.subscriptionRepo.Update(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// patched returns the subscription with apply applied, without storing it. Its version is
+// the one that was read, for the repository to detect concurrent changes.
+func (s *SubscriptionService) patched(ctx context.Context, id int64, version int64, apply func(*models.SubscriptionModel) error) (*models.SubscriptionModel, error) {
+	sub, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing subscription for update: %w", err)
+	}
+	if version != 0 && sub.Version != version {
+		return nil, merrors.NewPreconditionFailedError(fmt.Sprintf("subscription is at version %d, not %d", sub.Version, version))
+	}
+
+	if err := apply(sub); err != nil {
+		return nil, err
+	}
+
+	if err := sub.Validate(); err != nil {
+		return nil, fmt.Errorf("patched subscription validation failed: %w", err)
+	}
+
+	return sub, nil
+}
+
+// patch returns a function applying the update request to a subscription, resolving its service
+// through the catalog.
+func (s *SubscriptionService) patch(ctx context.Context, subUpdateReq *models.SubscriptionUpdateReq) func(*models.SubscriptionModel) error {
+	return func(sub *models.SubscriptionModel) error {
+		if err := subUpdateReq.PatchModel(sub); err != nil {
+			return fmt.Errorf("failed to patch subscription model: %w", err)
+		}
+
+		if subUpdateReq.ServiceID != nil || subUpdateReq.ServiceName != nil {
+			var id int64
+			var name string
+			if subUpdateReq.ServiceID != nil {
+				id = *subUpdateReq.ServiceID
+			} else {
+				name = *subUpdateReq.ServiceName
+			}
+			service, err := s.catalog.Resolve(ctx, id, name)
+			if err != nil {
+				return fmt.Errorf("failed to resolve subscription service: %w", err)
+			}
+			sub.ServiceID, sub.ServiceName = service.ID, service.Name
+		}
+
+		return nil
+	}
+}
+
+// CreateBatch creates a subscription for every request in one transaction, and returns them along
+// with an error per request. Requests that fail validation are reported without reaching the
+// repository; in atomic mode they abort the batch. Service names unknown to the catalog are
+// added to it even if the batch is aborted.
+func (s *SubscriptionService) CreateBatch(ctx context.Context, reqs []models.SubscriptionCreateReq, atomic bool) ([]*models.SubscriptionModel, []error, error) {
+	if err := models.ValidateBatchSize(len(reqs)); err != nil {
+		return nil, nil, err
+	}
+
+	subs := make([]*models.SubscriptionModel, len(reqs))
+	errs := make([]error, len(reqs))
+	for i := range reqs {
+		subs[i], errs[i] = s.prepare(ctx, &reqs[i], s.catalog.Resolve)
+	}
+
+	err := applyBatch(subs, errs, atomic, func(ready []*models.SubscriptionModel) ([]error, error) {
+		return s.subscriptionRepo.CreateBatch(ctx, ready, atomic)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create subscription batch: %w", err)
+	}
+
+	return subs, errs, nil
+}
+
+// UpdateBatch applies the same patch to every subscription of the request, like Update with the
+// given versions but without retrying on concurrent changes.
+func (s *SubscriptionService) UpdateBatch(ctx context.Context, req *models.SubscriptionBatchUpdateReq, atomic bool) ([]*models.SubscriptionModel, []error, error) {
+	if err := req.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("subscription batch update validation failed: %w", err)
+	}
+
+	subs := make([]*models.SubscriptionModel, len(req.IDs))
+	errs := make([]error, len(req.IDs))
+	for i, id := range req.IDs {
+		subs[i], errs[i] = s.patched(ctx, id, req.Version(i), s.patch(ctx, &req.Update))
 	}
 
-	sub, err := s.GetByID(id)
+	err := applyBatch(subs, errs, atomic, func(ready []*models.SubscriptionModel) ([]error, error) {
+		return s.subscriptionRepo.UpdateBatch(ctx, ready, atomic)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get existing subscription for update: %w", err)
+		return nil, nil, fmt.Errorf("failed to update subscription batch: %w", err)
 	}
 
-	err = subUpdateReq.PatchModel(sub)
+	return subs, errs, nil
+}
+
+// DeleteBatch soft-deletes the subscriptions of the request and returns an error per id.
+func (s *SubscriptionService) DeleteBatch(ctx context.Context, req *models.SubscriptionBatchDeleteReq, atomic bool) ([]error, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("subscription batch delete validation failed: %w", err)
+	}
+
+	versions := make([]int64, len(req.IDs))
+	for i := range versions {
+		versions[i] = req.Version(i)
+	}
+
+	errs, err := s.subscriptionRepo.DeleteBatch(ctx, req.IDs, versions, atomic)
 	if err != nil {
-		return fmt.Errorf("failed to patch subscription model: %w", err)
+		return nil, fmt.Errorf("failed to delete subscription batch: %w", err)
+	}
+
+	return errs, nil
+}
+
+// Import creates a subscription for every row read from rows and reports the rows that failed by
+// line. Rows are stored MaxBatchSize at a time, each in a partial batch, so that a failed row
+// does not keep the others from being imported. A dry run validates the rows without storing
+// anything, not even new service names. Batches stored before an error stay stored, so along
+// with the error Import returns the report up to the last of them.
+func (s *SubscriptionService) Import(ctx context.Context, rows *models.SubscriptionCSVReader, dryRun bool) (*models.ImportReport, error) {
+	resolve := s.catalog.Resolve
+	if dryRun {
+		resolve = s.catalog.Find
 	}
 
-	return s.subscriptionRepo.Update(sub)
+	report := &models.ImportReport{DryRun: dryRun, Errors: []*models.ImportLineError{}}
+	var (
+		subs  []*models.SubscriptionModel
+		lines []int
+	)
+	flush := func() error {
+		if len(subs) == 0 {
+			return nil
+		}
+		errs, err := s.subscriptionRepo.CreateBatch(ctx, subs, false)
+		if err != nil {
+			return fmt.Errorf("failed to import subscriptions: %w", err)
+		}
+		for i, err := range errs {
+			if err != nil {
+				report.Fail(lines[i], err)
+			} else {
+				report.Imported++
+			}
+		}
+		report.Through = lines[len(lines)-1]
+		subs, lines = subs[:0], lines[:0]
+		return nil
+	}
+	finish := func(err error) (*models.ImportReport, error) {
+		// Rows failing in the repository are reported when their batch is flushed, after later rows.
+		slices.SortStableFunc(report.Errors, func(a, b *models.ImportLineError) int { return a.Line - b.Line })
+		if err != nil {
+			report.Error = merrors.ErrorToResponseString(err)
+		}
+		return report, err
+	}
+
+	last := 0
+	for {
+		line, req, err := rows.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var validationErr *merrors.ValidationError
+		if err != nil && !errors.As(err, &validationErr) {
+			return finish(fmt.Errorf("failed to read subscriptions to import: %w", err))
+		}
+
+		report.Rows++
+		last = line
+		if err != nil {
+			report.Fail(line, err)
+			continue
+		}
+
+		sub, err := s.prepare(ctx, req, resolve)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return finish(ctxErr)
+		}
+		if err != nil {
+			report.Fail(line, err)
+			continue
+		}
+
+		if dryRun {
+			report.Imported++
+			continue
+		}
+		subs, lines = append(subs, sub), append(lines, line)
+		if len(subs) == models.MaxBatchSize {
+			if err := flush(); err != nil {
+				return finish(err)
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return finish(err)
+	}
+	report.Through = last
+	return finish(nil)
+}
+
+// applyBatch passes the items that were prepared without error to apply, and merges the errors
+// it returns into errs. In atomic mode nothing is applied once an item failed.
+func applyBatch[T any](items []T, errs []error, atomic bool, apply func(ready []T) ([]error, error)) error {
+	var (
+		ready   []T
+		indexes []int
+	)
+	for i, err := range errs {
+		if err == nil {
+			ready = append(ready, items[i])
+			indexes = append(indexes, i)
+		}
+	}
+
+	if atomic && len(ready) < len(items) {
+		models.AbortBatch(errs)
+		return nil
+	}
+	if len(ready) == 0 {
+		return nil
+	}
+
+	applied, err := apply(ready)
+	if err != nil {
+		return err
+	}
+	for j, err := range applied {
+		errs[indexes[j]] = err
+	}
+	return nil
 }
@@ -0,0 +1,519 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/fx"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/repo"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/services"
+	"github.com/google/uuid"
+)
+
+func newService(t *testing.T, reqs ...models.SubscriptionCreateReq) *services.SubscriptionService {
+	t.Helper()
+	rates := fx.NewTableRateProvider("RUB", map[string]*big.Rat{
+		"USD": big.NewRat(90, 1),
+		"EUR": big.NewRat(100, 1),
+	})
+	subscriptions := repo.NewMemorySubscriptionRepo()
+	catalog := services.NewCatalogService(repo.NewMemoryServiceRepo(subscriptions))
+	svc := services.NewSubscriptionService(subscriptions, catalog, rates, "RUB")
+	for _, req := range reqs {
+		if _, err := svc.Create(t.Context(), &req); err != nil {
+			t.Fatalf("Create(%+v) error = %v", req, err)
+		}
+	}
+	return svc
+}
+
+func strPtr(s string) *string { return &s }
+
+func mustMonth(t *testing.T, s string) time.Time {
+	t.Helper()
+	m, err := time.Parse(models.TimeFormat, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestSubscriptionService_CreateValidation(t *testing.T) {
+	svc := newService(t)
+
+	tests := []struct {
+		name string
+		req  models.SubscriptionCreateReq
+	}{
+		{name: "nil user", req: models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.Nil, Price: 10, StartDate: "01-2025"}},
+		{name: "bad start date", req: models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 10, StartDate: "2025-01"}},
+		{name: "end before start", req: models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 10, StartDate: "05-2025", EndDate: strPtr("01-2025")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Create(t.Context(), &tt.req)
+			var validationErr *merrors.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Create() error = %v, want ValidationError", err)
+			}
+		})
+	}
+}
+
+func TestSubscriptionService_GetSum(t *testing.T) {
+	user := uuid.New()
+	svc := newService(t,
+		models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: user, Price: 400, StartDate: "11-2024"},
+		models.SubscriptionCreateReq{ServiceName: "Spotify", UserID: user, Price: 200, StartDate: "02-2025", EndDate: strPtr("03-2025")},
+		models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 1000, StartDate: "01-2025"},
+	)
+
+	tests := []struct {
+		name   string
+		filter models.SubscriptionFilter
+		want   models.Money
+	}{
+		{name: "user over quarter", filter: models.SubscriptionFilter{UserID: user, From: mustMonth(t, "01-2025"), Till: mustMonth(t, "03-2025")}, want: 3*400 + 2*200},
+		{name: "service over quarter", filter: models.SubscriptionFilter{ServiceName: "Netflix", From: mustMonth(t, "01-2025"), Till: mustMonth(t, "03-2025")}, want: 3*400 + 3*1000},
+		{name: "no lower bound", filter: models.SubscriptionFilter{UserID: user, Till: mustMonth(t, "01-2025")}, want: 3 * 400},
+		{name: "before everything", filter: models.SubscriptionFilter{From: mustMonth(t, "01-2020"), Till: mustMonth(t, "12-2020")}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.GetSum(t.Context(), &tt.filter)
+			if err != nil {
+				t.Fatalf("GetSum() error = %v", err)
+			}
+			if got.Sum != tt.want || got.Currency != "RUB" {
+				t.Errorf("GetSum() = %v %s, want %v RUB", got.Sum, got.Currency, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscriptionService_GetSumConvertsCurrencies(t *testing.T) {
+	user := uuid.New()
+	svc := newService(t,
+		models.SubscriptionCreateReq{ServiceName: "Yandex Plus", UserID: user, Price: 299_00, StartDate: "01-2025"},
+		models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: user, Price: 15_49, Currency: "USD", StartDate: "01-2025"},
+		models.SubscriptionCreateReq{ServiceName: "Spotify", UserID: user, Price: 10_99, Currency: "EUR", StartDate: "01-2025"},
+	)
+	period := models.SubscriptionFilter{From: mustMonth(t, "01-2025"), Till: mustMonth(t, "02-2025")}
+
+	tests := []struct {
+		currency string
+		want     models.Money
+	}{
+		{currency: "", want: 2*299_00 + 2*15_49*90 + 2*10_99*100},
+		{currency: "RUB", want: 2*299_00 + 2*15_49*90 + 2*10_99*100},
+		// 598 RUB = 6.644.. USD, 21.98 EUR = 24.422.. USD
+		{currency: "USD", want: 6_64 + 2*15_49 + 24_42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.currency, func(t *testing.T) {
+			filter := period
+			filter.Currency = tt.currency
+			got, err := svc.GetSum(t.Context(), &filter)
+			if err != nil {
+				t.Fatalf("GetSum() error = %v", err)
+			}
+			if got.Sum != tt.want {
+				t.Errorf("GetSum() = %v, want %v", got.Sum, tt.want)
+			}
+			want := models.Amounts{"RUB": 2 * 299_00, "USD": 2 * 15_49, "EUR": 2 * 10_99}
+			if !maps.Equal(got.Subtotals, want) {
+				t.Errorf("GetSum() subtotals = %v, want %v", got.Subtotals, want)
+			}
+		})
+	}
+
+	filter := period
+	filter.Currency = "JPY"
+	var validationErr *merrors.ValidationError
+	if _, err := svc.GetSum(t.Context(), &filter); !errors.As(err, &validationErr) {
+		t.Errorf("GetSum() in currency without rate error = %v, want ValidationError", err)
+	}
+
+	// A stored price in a currency without rate is not the fault of the client.
+	if _, err := svc.Create(t.Context(), &models.SubscriptionCreateReq{ServiceName: "BBC", UserID: user, Price: 5_99, Currency: "GBP", StartDate: "01-2025"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetSum(t.Context(), &period); err == nil || errors.As(err, &validationErr) {
+		t.Errorf("GetSum() of price without rate error = %v, want a server error", err)
+	}
+}
+
+func TestSubscriptionService_GetByFiltersPaginates(t *testing.T) {
+	user := uuid.New()
+	var reqs []models.SubscriptionCreateReq
+	for i := range 7 {
+		reqs = append(reqs, models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: user, Price: models.Money(100 + i%3), StartDate: "01-2025"})
+	}
+	svc := newService(t, reqs...)
+
+	filter := &models.SubscriptionFilter{UserID: user, Limit: 3, Sort: models.SortByPrice, Desc: true}
+	var seen []*models.SubscriptionModel
+	for {
+		page, err := svc.GetByFilters(t.Context(), filter)
+		if err != nil {
+			t.Fatalf("GetByFilters() error = %v", err)
+		}
+		if page.TotalCount != 7 {
+			t.Errorf("TotalCount = %d, want 7", page.TotalCount)
+		}
+		seen = append(seen, page.Subscriptions...)
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor, err = models.ParseCursor(page.NextCursor)
+		if err != nil {
+			t.Fatalf("ParseCursor() error = %v", err)
+		}
+	}
+
+	if len(seen) != 7 {
+		t.Fatalf("got %d subscriptions across pages, want 7", len(seen))
+	}
+	for i := 1; i < len(seen); i++ {
+		if filter.Compare(seen[i-1], seen[i]) >= 0 {
+			t.Errorf("subscriptions %d and %d are out of order", seen[i-1].ID, seen[i].ID)
+		}
+	}
+}
+
+func TestSubscriptionService_UpdateAndDelete(t *testing.T) {
+	svc := newService(t, models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 400, StartDate: "01-2025"})
+
+	price := models.Money(500)
+	updated, err := svc.Update(t.Context(), 1, 0, &models.SubscriptionUpdateReq{Price: &price, EndDate: strPtr("06-2025")})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.Price != 500 {
+		t.Errorf("Update() price = %v, want 500", updated.Price)
+	}
+
+	sub, err := svc.GetByID(t.Context(), 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if sub.Price != 500 || sub.EndDate == nil || !sub.EndDate.Equal(time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("GetByID() = %+v, want patched price and end date", sub)
+	}
+
+	if err := svc.Delete(t.Context(), 1, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	var notFound *merrors.NotFoundError
+	if _, err := svc.GetByID(t.Context(), 1); !errors.As(err, &notFound) {
+		t.Errorf("GetByID() after delete error = %v, want NotFoundError", err)
+	}
+	if _, err := svc.Update(t.Context(), 1, 0, &models.SubscriptionUpdateReq{Price: &price}); !errors.As(err, &notFound) {
+		t.Errorf("Update() after delete error = %v, want NotFoundError", err)
+	}
+}
+
+func TestSubscriptionService_PriceHistory(t *testing.T) {
+	svc := newService(t, models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 400, StartDate: "01-2025"})
+	year := models.SubscriptionFilter{From: mustMonth(t, "01-2025"), Till: mustMonth(t, "12-2025")}
+
+	price := models.Money(500)
+	if _, err := svc.Update(t.Context(), 1, 0, &models.SubscriptionUpdateReq{Price: &price, EffectiveFrom: strPtr("07-2025")}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	sum, err := svc.GetSum(t.Context(), &year)
+	if err != nil {
+		t.Fatalf("GetSum() error = %v", err)
+	}
+	if sum.Sum != 6*400+6*500 {
+		t.Errorf("GetSum() = %v, want the old price before the change", sum.Sum)
+	}
+	sub, err := svc.GetByID(t.Context(), 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if len(sub.Prices) != 2 || !sub.Prices[1].EffectiveFrom.Equal(mustMonth(t, "07-2025")) {
+		t.Errorf("GetByID() prices = %+v, want two entries", sub.Prices)
+	}
+
+	if _, err := svc.Update(t.Context(), 1, 0, &models.SubscriptionUpdateReq{Price: &price}); err != nil {
+		t.Fatalf("Update() without effective_from error = %v", err)
+	}
+	if sum, _ := svc.GetSum(t.Context(), &year); sum.Sum != 12*500 {
+		t.Errorf("GetSum() = %v, want the history replaced", sum.Sum)
+	}
+
+	var validation *merrors.ValidationError
+	if _, err := svc.Update(t.Context(), 1, 0, &models.SubscriptionUpdateReq{EffectiveFrom: strPtr("07-2025")}); !errors.As(err, &validation) {
+		t.Errorf("Update() with effective_from but no price error = %v, want ValidationError", err)
+	}
+}
+
+func TestSubscriptionService_Trials(t *testing.T) {
+	user := uuid.New()
+	intro := models.Money(100)
+	svc := newService(t,
+		models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: user, Price: 400, StartDate: "01-2025", TrialEnd: strPtr("01-2025"),
+			IntroPrice: &intro, IntroUntil: strPtr("03-2025")},
+		models.SubscriptionCreateReq{ServiceName: "Spotify", UserID: user, Price: 200, StartDate: "01-2025", TrialEnd: strPtr("12-2099")},
+	)
+
+	sum, err := svc.GetSum(t.Context(), &models.SubscriptionFilter{UserID: user, From: mustMonth(t, "01-2025"), Till: mustMonth(t, "06-2025")})
+	if err != nil {
+		t.Fatalf("GetSum() error = %v", err)
+	}
+	if want := models.Money(2*100 + 3*400); sum.Sum != want {
+		t.Errorf("GetSum() = %v, want %v", sum.Sum, want)
+	}
+
+	page, err := svc.GetByFilters(t.Context(), &models.SubscriptionFilter{Status: models.StatusTrialing, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetByFilters() error = %v", err)
+	}
+	if len(page.Subscriptions) != 1 || page.Subscriptions[0].ServiceName != "Spotify" {
+		t.Errorf("GetByFilters(status=trialing) = %+v, want only the running trial", page.Subscriptions)
+	}
+
+	var validation *merrors.ValidationError
+	_, err = svc.Create(t.Context(), &models.SubscriptionCreateReq{ServiceName: "Okko", UserID: user, Price: 300, StartDate: "01-2025", IntroPrice: &intro})
+	if !errors.As(err, &validation) {
+		t.Errorf("Create() with intro_price but no intro_until error = %v, want ValidationError", err)
+	}
+
+	if _, err := svc.Update(t.Context(), 1, 0, &models.SubscriptionUpdateReq{TrialEnd: strPtr(""), IntroUntil: strPtr("")}); err != nil {
+		t.Fatalf("Update() removing the trial error = %v", err)
+	}
+	sub, err := svc.GetByID(t.Context(), 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if sub.TrialEnd != nil || sub.IntroPrice != nil || sub.IntroUntil != nil {
+		t.Errorf("GetByID() = %+v, want the trial and the intro price removed", sub)
+	}
+}
+
+func TestSubscriptionService_Versions(t *testing.T) {
+	svc := newService(t, models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: uuid.New(), Price: 400, StartDate: "01-2025"})
+
+	sub, err := svc.GetByID(t.Context(), 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if sub.Version != 1 {
+		t.Fatalf("created version = %d, want 1", sub.Version)
+	}
+
+	price := models.Money(500)
+	updated, err := svc.Update(t.Context(), 1, 1, &models.SubscriptionUpdateReq{Price: &price})
+	if err != nil {
+		t.Fatalf("Update() at the current version error = %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("Update() version = %d, want 2", updated.Version)
+	}
+
+	var precondition *merrors.PreconditionFailedError
+	if _, err := svc.Update(t.Context(), 1, 1, &models.SubscriptionUpdateReq{Price: &price}); !errors.As(err, &precondition) {
+		t.Errorf("Update() at a stale version error = %v, want PreconditionFailedError", err)
+	}
+	if err := svc.Delete(t.Context(), 1, 1); !errors.As(err, &precondition) {
+		t.Errorf("Delete() at a stale version error = %v, want PreconditionFailedError", err)
+	}
+	if err := svc.Delete(t.Context(), 1, 2); err != nil {
+		t.Fatalf("Delete() at the current version error = %v", err)
+	}
+
+	restored, err := svc.Restore(t.Context(), 1, 0)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if restored.Version != 4 {
+		t.Errorf("Restore() version = %d, want 4", restored.Version)
+	}
+}
+
+func TestSubscriptionService_CreateBatch(t *testing.T) {
+	svc := newService(t)
+	user := uuid.New()
+	reqs := []models.SubscriptionCreateReq{
+		{ServiceName: "Netflix", UserID: user, Price: 400, StartDate: "01-2025"},
+		{ServiceName: "Spotify", UserID: user, Price: 200, StartDate: "bad"},
+		{ServiceName: "Kinopoisk", UserID: user, Price: 100, StartDate: "01-2025"},
+	}
+
+	var validationErr *merrors.ValidationError
+	var aborted *merrors.FailedDependencyError
+	_, errs, err := svc.CreateBatch(t.Context(), reqs, true)
+	if err != nil {
+		t.Fatalf("CreateBatch(atomic) error = %v", err)
+	}
+	if !errors.As(errs[1], &validationErr) || !errors.As(errs[0], &aborted) || !errors.As(errs[2], &aborted) {
+		t.Errorf("CreateBatch(atomic) errors = %v, want item 1 invalid and the rest aborted", errs)
+	}
+	if page, _ := svc.GetByFilters(t.Context(), &models.SubscriptionFilter{Limit: 10}); page.TotalCount != 0 {
+		t.Errorf("atomic batch with a failed item created %d subscriptions, want 0", page.TotalCount)
+	}
+
+	subs, errs, err := svc.CreateBatch(t.Context(), reqs, false)
+	if err != nil {
+		t.Fatalf("CreateBatch(partial) error = %v", err)
+	}
+	if errs[0] != nil || errs[2] != nil || !errors.As(errs[1], &validationErr) {
+		t.Errorf("CreateBatch(partial) errors = %v, want only item 1 to fail", errs)
+	}
+	if subs[0].ID == 0 || subs[2].ID == 0 {
+		t.Errorf("CreateBatch(partial) ids = %d, %d, want both set", subs[0].ID, subs[2].ID)
+	}
+	if page, _ := svc.GetByFilters(t.Context(), &models.SubscriptionFilter{Limit: 10}); page.TotalCount != 2 {
+		t.Errorf("partial batch created %d subscriptions, want 2", page.TotalCount)
+	}
+}
+
+func TestSubscriptionService_UpdateAndDeleteBatch(t *testing.T) {
+	user := uuid.New()
+	svc := newService(t,
+		models.SubscriptionCreateReq{ServiceName: "Netflix", UserID: user, Price: 400, StartDate: "01-2025"},
+		models.SubscriptionCreateReq{ServiceName: "Spotify", UserID: user, Price: 200, StartDate: "01-2025"},
+	)
+
+	price := models.Money(300)
+	update := &models.SubscriptionBatchUpdateReq{
+		SubscriptionBatchDeleteReq: models.SubscriptionBatchDeleteReq{IDs: []int64{1, 2, 3}},
+		Update:                     models.SubscriptionUpdateReq{Price: &price},
+	}
+	var notFound *merrors.NotFoundError
+	_, errs, err := svc.UpdateBatch(t.Context(), update, true)
+	if err != nil {
+		t.Fatalf("UpdateBatch(atomic) error = %v", err)
+	}
+	if !errors.As(errs[2], &notFound) {
+		t.Errorf("UpdateBatch(atomic) error for a missing id = %v, want NotFoundError", errs[2])
+	}
+	if sub, _ := svc.GetByID(t.Context(), 1); sub.Price != 400 {
+		t.Errorf("price after aborted batch = %v, want 400", sub.Price)
+	}
+
+	update.IDs = []int64{1, 2}
+	subs, errs, err := svc.UpdateBatch(t.Context(), update, true)
+	if err != nil || errs[0] != nil || errs[1] != nil {
+		t.Fatalf("UpdateBatch(atomic) = %v, %v", errs, err)
+	}
+	if subs[0].Price != 300 || subs[1].Price != 300 || subs[0].Version != 2 {
+		t.Errorf("UpdateBatch() = %+v, %+v, want price 300 at version 2", subs[0], subs[1])
+	}
+
+	errs, err = svc.DeleteBatch(t.Context(), &models.SubscriptionBatchDeleteReq{IDs: []int64{1, 2}, Versions: []int64{2, 1}}, false)
+	if err != nil {
+		t.Fatalf("DeleteBatch(partial) error = %v", err)
+	}
+	var precondition *merrors.PreconditionFailedError
+	if errs[0] != nil || !errors.As(errs[1], &precondition) {
+		t.Errorf("DeleteBatch(partial) errors = %v, want only the stale version to fail", errs)
+	}
+	if _, err := svc.GetByID(t.Context(), 2); err != nil {
+		t.Errorf("GetByID() of the subscription left out of the batch error = %v", err)
+	}
+
+	var validationErr *merrors.ValidationError
+	if _, err := svc.DeleteBatch(t.Context(), &models.SubscriptionBatchDeleteReq{IDs: []int64{2, 2}}, false); !errors.As(err, &validationErr) {
+		t.Errorf("DeleteBatch() with duplicate ids error = %v, want ValidationError", err)
+	}
+}
+
+func TestSubscriptionService_ResolvesServiceNames(t *testing.T) {
+	subscriptions := repo.NewMemorySubscriptionRepo()
+	catalog := services.NewCatalogService(repo.NewMemoryServiceRepo(subscriptions))
+	svc := services.NewSubscriptionService(subscriptions, catalog, fx.NewTableRateProvider("RUB", nil), "RUB")
+
+	defaultPrice := models.Money(299_00)
+	yandex, err := catalog.Create(t.Context(), &models.ServiceCreateReq{Name: "Yandex Plus", Aliases: []string{"Яндекс Плюс"}, DefaultPrice: &defaultPrice})
+	if err != nil {
+		t.Fatalf("Create service error = %v", err)
+	}
+
+	user := uuid.New()
+	for _, name := range []string{"Yandex Plus", " yandex  plus", "Яндекс Плюс", "ЯНДЕКС ПЛЮС"} {
+		sub, err := svc.Create(t.Context(), &models.SubscriptionCreateReq{ServiceName: name, UserID: user, StartDate: "01-2025"})
+		if err != nil {
+			t.Fatalf("Create(%q) error = %v", name, err)
+		}
+		if sub.ServiceID != yandex.ID || sub.ServiceName != "Yandex Plus" || sub.Price != defaultPrice {
+			t.Errorf("Create(%q) = %+v, want service %d with its default price", name, sub, yandex.ID)
+		}
+	}
+
+	sum, err := svc.GetSum(t.Context(), &models.SubscriptionFilter{ServiceName: "яндекс плюс", From: mustMonth(t, "01-2025"), Till: mustMonth(t, "01-2025")})
+	if err != nil {
+		t.Fatalf("GetSum() error = %v", err)
+	}
+	if sum.Sum != 4*defaultPrice {
+		t.Errorf("GetSum() = %v, want %v", sum.Sum, 4*defaultPrice)
+	}
+
+	sub, err := svc.Create(t.Context(), &models.SubscriptionCreateReq{ServiceName: "Kinopoisk", UserID: user, Price: 10, StartDate: "01-2025"})
+	if err != nil {
+		t.Fatalf("Create() with a new service error = %v", err)
+	}
+	if added, err := catalog.GetByID(t.Context(), sub.ServiceID); err != nil || added.Name != "Kinopoisk" {
+		t.Errorf("catalog entry for a new service = %+v, %v", added, err)
+	}
+
+	var validationErr *merrors.ValidationError
+	if _, err := svc.Create(t.Context(), &models.SubscriptionCreateReq{ServiceName: "Kinopoisk", UserID: user, StartDate: "01-2025"}); !errors.As(err, &validationErr) {
+		t.Errorf("Create() without price or default price error = %v, want ValidationError", err)
+	}
+	if _, err := svc.Create(t.Context(), &models.SubscriptionCreateReq{ServiceID: 100, UserID: user, Price: 10, StartDate: "01-2025"}); !errors.As(err, &validationErr) {
+		t.Errorf("Create() with unknown service_id error = %v, want ValidationError", err)
+	}
+}
+
+// cancelAtEOF reads r and cancels the import once it is exhausted.
+type cancelAtEOF struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (c *cancelAtEOF) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		c.cancel()
+	}
+	return n, err
+}
+
+func TestSubscriptionService_ImportReportsWhenCancelled(t *testing.T) {
+	svc := newService(t)
+	var csv strings.Builder
+	csv.WriteString("service_name,user_id,price,start_date\n")
+	for range models.MaxBatchSize + 1 {
+		csv.WriteString("Netflix," + uuid.NewString() + ",400,01-2025\n")
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	rows, err := models.NewSubscriptionCSVReader(&cancelAtEOF{r: strings.NewReader(csv.String()), cancel: cancel})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first batch is stored before the context is cancelled, the last row is not.
+	report, err := svc.Import(ctx, rows, false)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Import() error = %v, want context.Canceled", err)
+	}
+	if report == nil || report.Imported != models.MaxBatchSize || report.Through != models.MaxBatchSize+1 || report.Error == "" {
+		t.Errorf("Import() report = %+v, want %d rows imported through line %d", report, models.MaxBatchSize, models.MaxBatchSize+1)
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/repo"
+)
+
+// WebhookService manages webhooks and their deliveries. The deliveries themselves are queued by
+// the subscription repository and sent by the webhooks dispatcher.
+type WebhookService struct {
+	webhookRepo repo.WebhookRepository
+}
+
+func NewWebhookService(webhookRepo repo.WebhookRepository) *WebhookService {
+	return &WebhookService{webhookRepo: webhookRepo}
+}
+
+func (s *WebhookService) Create(ctx context.Context, req *models.WebhookCreateReq) (*models.WebhookModel, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("webhook creation validation failed: %w", err)
+	}
+
+	webhook := req.ToModel()
+	if err := s.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (s *WebhookService) GetByID(ctx context.Context, id int64) (*models.WebhookModel, error) {
+	webhook, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook by ID %d: %w", id, err)
+	}
+
+	return webhook, nil
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]*models.WebhookModel, error) {
+	webhooks, err := s.webhookRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (s *WebhookService) Delete(ctx context.Context, id int64) error {
+	if err := s.webhookRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete webhook with ID %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the latest deliveries to the webhook, only those with the status unless
+// it is empty.
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID int64, status string) ([]*models.WebhookDelivery, error) {
+	if err := models.ValidateDeliveryStatus(status); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.webhookRepo.ListDeliveries(ctx, webhookID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries of webhook %d: %w", webhookID, err)
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver queues a delivery of the webhook to be sent again right away, with a fresh set of
+// attempts. Dead deliveries are brought back this way, and delivered ones replayed. Pending
+// deliveries that are not due yet are refused, as they may be in flight.
+func (s *WebhookService) Redeliver(ctx context.Context, webhookID, id int64) (*models.WebhookDelivery, error) {
+	delivery, err := s.webhookRepo.RedeliverDelivery(ctx, webhookID, id, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeliver delivery %d of webhook %d: %w", id, webhookID, err)
+	}
+
+	return delivery, nil
+}
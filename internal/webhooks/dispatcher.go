@@ -0,0 +1,136 @@
+// Package webhooks sends the subscription events queued in the webhook outbox to their webhooks.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/repo"
+)
+
+const (
+	// batchSize is how many deliveries are claimed at once.
+	batchSize = 20
+	// claimLease is how long claimed deliveries are hidden from other dispatchers. It outlasts
+	// sending a whole batch, and is how soon the deliveries of a crashed dispatcher are retried.
+	claimLease = 15 * time.Minute
+)
+
+// Dispatcher periodically sends the due deliveries of the outbox. A delivery that fails is retried
+// after Backoff, doubled for every further failure, until it made MaxAttempts attempts and is
+// marked dead. Any response other than 2xx is a failure.
+type Dispatcher struct {
+	webhooks    repo.WebhookRepository
+	client      *http.Client
+	interval    time.Duration
+	maxAttempts int
+	backoff     time.Duration
+}
+
+func NewDispatcher(webhooks repo.WebhookRepository, client *http.Client, interval time.Duration, maxAttempts int, backoff time.Duration) *Dispatcher {
+	return &Dispatcher{
+		webhooks:    webhooks,
+		client:      client,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+	}
+}
+
+// Run sends the due deliveries right away and then every interval, until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		delivered, err := d.RunOnce(ctx, time.Now().UTC())
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Failed to send webhook deliveries", "delivered", delivered, "error", err)
+		} else if delivered > 0 {
+			slog.Info("Sent webhook deliveries", "delivered", delivered)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends the deliveries due at now and returns how many were accepted. Failed attempts are
+// recorded on the deliveries; only failures of the repository are returned.
+func (d *Dispatcher) RunOnce(ctx context.Context, now time.Time) (int, error) {
+	var delivered int
+	for {
+		due, err := d.webhooks.ClaimDeliveries(ctx, now, batchSize, claimLease)
+		if err != nil {
+			return delivered, err
+		}
+
+		for _, delivery := range due {
+			d.attempt(ctx, delivery, now)
+			// Saving must happen even if ctx is done, or the attempt would be made again.
+			if err := d.webhooks.SaveDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+				return delivered, err
+			}
+			if delivery.Status == models.DeliveryDelivered {
+				delivered++
+			}
+			if ctx.Err() != nil {
+				return delivered, ctx.Err()
+			}
+		}
+
+		if len(due) < batchSize {
+			return delivered, nil
+		}
+	}
+}
+
+// attempt sends the delivery and records the outcome on it.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery, now time.Time) {
+	err := d.send(ctx, delivery, now)
+	if err == nil {
+		delivery.Delivered(now)
+		return
+	}
+
+	delivery.Failed(now, err, d.maxAttempts, d.backoff)
+	if delivery.Status == models.DeliveryDead {
+		slog.Error("Webhook delivery is dead", "delivery_id", delivery.ID, "webhook_id", delivery.WebhookID, "attempts", delivery.Attempts, "error", err)
+		return
+	}
+	slog.Warn("Webhook delivery failed", "delivery_id", delivery.ID, "webhook_id", delivery.WebhookID, "attempts", delivery.Attempts,
+		"next_attempt_at", delivery.NextAttemptAt, "error", err)
+}
+
+// send posts the payload of the delivery to its webhook, signed as of now.
+func (d *Dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery, now time.Time) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.EventType())
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("X-Webhook-Signature", models.SignWebhook(delivery.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
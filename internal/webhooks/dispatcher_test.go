@@ -0,0 +1,173 @@
+package webhooks_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/merrors"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/repo"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+const secret = "0123456789abcdef"
+
+// newOutbox registers a webhook for url and creates a subscription, which queues one delivery.
+func newOutbox(t *testing.T, url string) *repo.MemoryWebhookRepo {
+	t.Helper()
+	subscriptions := repo.NewMemorySubscriptionRepo()
+	hooks := repo.NewMemoryWebhookRepo(subscriptions)
+	if err := hooks.Create(t.Context(), &models.WebhookModel{URL: url, Secret: secret, Events: []string{models.ActionCreated}}); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := &models.SubscriptionModel{ServiceName: "Netflix", UserID: uuid.New(), Price: 400, Currency: "RUB",
+		BillingPeriod: models.DefaultBillingPeriod, StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if err := subscriptions.Create(t.Context(), sub); err != nil {
+		t.Fatal(err)
+	}
+	// Updates are not delivered to a webhook of creations only.
+	if err := subscriptions.Update(t.Context(), sub); err != nil {
+		t.Fatal(err)
+	}
+	return hooks
+}
+
+func delivery(t *testing.T, hooks *repo.MemoryWebhookRepo) *models.WebhookDelivery {
+	t.Helper()
+	deliveries, err := hooks.ListDeliveries(t.Context(), 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("deliveries = %d, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+func TestDispatcher_SignsDeliveries(t *testing.T) {
+	var event models.SubscriptionEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(r.Header.Get("X-Webhook-Timestamp") + "." + string(body)))
+		if r.Header.Get("X-Webhook-Signature") != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("X-Webhook-Event") != "subscription.created" || json.Unmarshal(body, &event) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	hooks := newOutbox(t, srv.URL)
+	now := time.Now().UTC()
+	n, err := webhooks.NewDispatcher(hooks, srv.Client(), time.Minute, 3, time.Minute).RunOnce(t.Context(), now)
+	if err != nil || n != 1 {
+		t.Fatalf("RunOnce() = %d, %v, want 1 delivered (last error %q)", n, err, delivery(t, hooks).LastError)
+	}
+	if event.Action != models.ActionCreated || event.After == nil || event.After.ServiceName != "Netflix" {
+		t.Errorf("webhook got %+v, want the creation of the subscription", event)
+	}
+	if got := delivery(t, hooks); got.Status != models.DeliveryDelivered || got.Attempts != 1 || got.DeliveredAt == nil {
+		t.Errorf("delivery = %+v, want delivered on the first attempt", got)
+	}
+
+	if n, err := webhooks.NewDispatcher(hooks, srv.Client(), time.Minute, 3, time.Minute).RunOnce(t.Context(), now.Add(time.Hour)); err != nil || n != 0 {
+		t.Errorf("RunOnce() again = %d, %v, want nothing sent", n, err)
+	}
+}
+
+func TestDispatcher_RetriesAndDeadLetters(t *testing.T) {
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	hooks := newOutbox(t, srv.URL)
+	dispatcher := webhooks.NewDispatcher(hooks, srv.Client(), time.Minute, 3, time.Minute)
+	start := time.Now().UTC()
+
+	steps := []struct {
+		after        time.Duration
+		wantAttempts int
+		wantStatus   string
+		wantNext     time.Duration
+	}{
+		{after: 0, wantAttempts: 1, wantStatus: models.DeliveryPending, wantNext: time.Minute},
+		{after: 30 * time.Second, wantAttempts: 1, wantStatus: models.DeliveryPending, wantNext: time.Minute},
+		{after: time.Minute, wantAttempts: 2, wantStatus: models.DeliveryPending, wantNext: 3 * time.Minute},
+		{after: 3 * time.Minute, wantAttempts: 3, wantStatus: models.DeliveryDead},
+		{after: time.Hour, wantAttempts: 3, wantStatus: models.DeliveryDead},
+	}
+	for _, step := range steps {
+		if n, err := dispatcher.RunOnce(t.Context(), start.Add(step.after)); err != nil || n != 0 {
+			t.Fatalf("RunOnce(+%s) = %d, %v, want nothing delivered", step.after, n, err)
+		}
+		got := delivery(t, hooks)
+		if got.Attempts != step.wantAttempts || got.Status != step.wantStatus {
+			t.Errorf("after +%s delivery is %s after %d attempts, want %s after %d", step.after, got.Status, got.Attempts, step.wantStatus, step.wantAttempts)
+		}
+		if step.wantNext != 0 && !got.NextAttemptAt.Equal(start.Add(step.wantNext)) {
+			t.Errorf("after +%s next attempt at +%s, want +%s", step.after, got.NextAttemptAt.Sub(start), step.wantNext)
+		}
+	}
+	if got := delivery(t, hooks); got.LastError == "" {
+		t.Errorf("dead delivery has no last error")
+	}
+
+	// A redelivered dead delivery is sent again with a fresh set of attempts, but it cannot be
+	// redelivered again while it is claimed.
+	healthy.Store(true)
+	redelivered := start.Add(time.Hour)
+	if _, err := hooks.RedeliverDelivery(t.Context(), 1, 1, redelivered); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, err := hooks.ClaimDeliveries(t.Context(), redelivered, 1, time.Minute); err != nil || len(claimed) != 1 {
+		t.Fatalf("ClaimDeliveries() = %v, %v, want the redelivered delivery", claimed, err)
+	}
+	var conflict *merrors.ConflictError
+	if _, err := hooks.RedeliverDelivery(t.Context(), 1, 1, redelivered); !errors.As(err, &conflict) {
+		t.Errorf("RedeliverDelivery() of a claimed delivery error = %v, want ConflictError", err)
+	}
+	if n, err := dispatcher.RunOnce(t.Context(), redelivered.Add(time.Minute)); err != nil || n != 1 {
+		t.Errorf("RunOnce() after redelivery = %d, %v, want 1 delivered", n, err)
+	}
+	if got := delivery(t, hooks); got.Status != models.DeliveryDelivered || got.Attempts != 1 {
+		t.Errorf("redelivered delivery = %+v, want delivered on its first attempt", got)
+	}
+}
+
+func TestDispatcher_RefusesPrivateAddresses(t *testing.T) {
+	var called atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called.Store(true)
+	}))
+	defer srv.Close()
+
+	hooks := newOutbox(t, srv.URL)
+	if n, err := webhooks.NewDispatcher(hooks, webhooks.NewClient(time.Second), time.Minute, 3, time.Minute).RunOnce(t.Context(), time.Now().UTC()); err != nil || n != 0 {
+		t.Fatalf("RunOnce() = %d, %v, want nothing delivered", n, err)
+	}
+	if called.Load() {
+		t.Error("webhook on a loopback address was called")
+	}
+	if got := delivery(t, hooks); got.Status != models.DeliveryPending || !strings.Contains(got.LastError, "not public") {
+		t.Errorf("delivery = %+v, want a failed attempt to a non-public address", got)
+	}
+}
@@ -1,11 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/TheTeemka/task_effective_mobile_subscribe/docs"
 	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/config"
 	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/database"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/fx"
 	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/handlers"
 	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/repo"
 	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/services"
@@ -18,31 +26,97 @@ import (
 func main() {
 	slog.Info("Loading configuration")
 	cfg := config.LoadConfig()
-	slog.Info("Configuration loaded successfully", "logLevel", cfg.LogLevel, "port", cfg.Port)
+	slog.Info("Configuration loaded successfully", "logLevel", cfg.LogLevel, "port", cfg.Port, "requestTimeout", cfg.RequestTimeout, "shutdownTimeout", cfg.ShutdownTimeout)
 
 	logging.SetSlog(cfg.LogLevel)
 
-	db := database.NewPSQLConnection(cfg.PSQLSource)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(cfg, os.Args[2:])
+			return
+		case "purge":
+			runPurge(cfg, os.Args[2:])
+			return
+		}
+	}
+
+	db := database.NewPSQLConnection(cfg.PSQLSource, cfg.AutoMigrate)
+	defer db.Close()
 
-	repo := repo.NewSubscriptionRepo(db)
-	svc := services.NewSubscriptionService(repo)
+	rates := fx.NewTableRateProvider(cfg.BaseCurrency, nil)
+	if cfg.FXRatesFile != "" {
+		var err error
+		if rates, err = fx.LoadTableRateProvider(cfg.FXRatesFile); err != nil {
+			panic(err)
+		}
+		if !rates.Has(cfg.BaseCurrency) {
+			panic(fmt.Sprintf("exchange rates file %s has no rate for BASE_CURRENCY %s", cfg.FXRatesFile, cfg.BaseCurrency))
+		}
+		slog.Info("Loaded exchange rates", "file", cfg.FXRatesFile)
+	}
+
+	catalog := services.NewCatalogService(repo.NewServiceRepo(db))
+	svc := services.NewSubscriptionService(repo.NewSubscriptionRepo(db), catalog, rates, cfg.BaseCurrency)
 	handler := handlers.NewSubscriptionHandler(svc)
+	handler.RequireIfMatch = cfg.RequireIfMatch
+	serviceHandler := handlers.NewServiceHandler(catalog)
+	webhookHandler := handlers.NewWebhookHandler(services.NewWebhookService(repo.NewWebhookRepo(db)))
+	health := handlers.NewHealthHandler(db)
 
-	r := gin.Default()
+	r := gin.New()
+	r.Use(gin.Logger(), handlers.Recovery())
 
-	api := r.Group("/api/subscriptions")
-	{
-		api.GET("/", handler.ListSubscriptions)
-		api.GET("/:id", handler.GetSubscription)
-		api.GET("/sum", handler.GetSum)
-		api.POST("/", handler.CreateSubscription)
-		api.PATCH("/:id", handler.UpdateSubscription)
-		api.DELETE("/:id", handler.DeleteSubscription)
-	}
+	health.RegisterRoutes(r)
+	api := r.Group("/api", handlers.Actor(), handlers.RequestTimeout(cfg.RequestTimeout))
+	handler.RegisterRoutes(api.Group("/subscriptions"))
+	serviceHandler.RegisterRoutes(api.Group("/services"))
+	webhookHandler.RegisterRoutes(api.Group("/webhooks"))
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	slog.Info("Starting server", "port", cfg.Port)
+	srv := &http.Server{
+		Addr:    cfg.Port,
+		Handler: r,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	remindersDone := startReminders(ctx, cfg, db)
+	webhooksDone := startWebhooks(ctx, cfg, db)
+
+	go func() {
+		slog.Info("Starting server", "port", cfg.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Server failed", "error", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+
+	slog.Info("Shutting down server", "timeout", cfg.ShutdownTimeout)
+	health.SetShuttingDown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Server shutdown did not complete", "error", err)
+	}
+
+	select {
+	case <-remindersDone:
+	case <-shutdownCtx.Done():
+		slog.Error("Reminder scheduler did not stop in time")
+	}
+	select {
+	case <-webhooksDone:
+	case <-shutdownCtx.Done():
+		slog.Error("Webhook dispatcher did not stop in time")
+	}
 
-	r.Run(cfg.Port)
+	slog.Info("Server stopped")
 }
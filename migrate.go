@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/config"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/database"
+)
+
+// runMigrate implements the `migrate up|down|status|version` subcommand.
+func runMigrate(cfg *config.Config, args []string) {
+	if len(args) != 1 || !slices.Contains(database.MigrationCommands, args[0]) {
+		fmt.Fprintf(os.Stderr, "usage: %s migrate %s\n", os.Args[0], strings.Join(database.MigrationCommands, "|"))
+		os.Exit(2)
+	}
+
+	db := database.NewPSQLConnection(cfg.PSQLSource, false)
+	err := database.Migrate(context.Background(), db, args[0])
+	db.Close()
+
+	if err != nil {
+		slog.Error("Migration failed", "command", args[0], "error", err)
+		os.Exit(1)
+	}
+}
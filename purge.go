@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/config"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/database"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/models"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/repo"
+)
+
+// purgeActor is recorded in the audit log for subscriptions removed by the purge subcommand.
+const purgeActor = "purge"
+
+// runPurge implements the `purge [-retention DURATION]` subcommand, which permanently removes
+// subscriptions soft-deleted longer than the retention period ago.
+func runPurge(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	retention := flags.Duration("retention", cfg.DeletedRetention, "remove subscriptions deleted longer than this ago")
+	flags.Parse(args)
+
+	if *retention <= 0 || flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "usage: %s purge [-retention DURATION]\n", os.Args[0])
+		os.Exit(2)
+	}
+
+	db := database.NewPSQLConnection(cfg.PSQLSource, false)
+	ctx := models.ContextWithActor(context.Background(), purgeActor)
+	deletedBefore := time.Now().Add(-*retention)
+	purged, err := repo.NewSubscriptionRepo(db).Purge(ctx, deletedBefore)
+	db.Close()
+
+	if err != nil {
+		slog.Error("Purge failed", "retention", *retention, "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Purged deleted subscriptions", "count", purged, "deleted_before", deletedBefore)
+}
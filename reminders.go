@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/config"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/reminders"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/repo"
+)
+
+// startReminders runs the reminder scheduler in the background until ctx is done, unless it is
+// disabled. The returned channel is closed once the scheduler has stopped.
+func startReminders(ctx context.Context, cfg *config.Config, db *sql.DB) <-chan struct{} {
+	done := make(chan struct{})
+	if cfg.ReminderInterval == 0 {
+		slog.Info("Reminder scheduler disabled")
+		close(done)
+		return done
+	}
+
+	scheduler := reminders.NewScheduler(repo.NewSubscriptionRepo(db), repo.NewReminderRepo(db), newNotifier(cfg), cfg.ReminderDays, cfg.ReminderInterval)
+	slog.Info("Starting reminder scheduler", "interval", cfg.ReminderInterval, "days", cfg.ReminderDays, "notifier", cfg.ReminderNotifier)
+	go func() {
+		defer close(done)
+		scheduler.Run(ctx)
+	}()
+	return done
+}
+
+func newNotifier(cfg *config.Config) reminders.Notifier {
+	switch cfg.ReminderNotifier {
+	case "webhook":
+		return reminders.NewWebhookNotifier(cfg.ReminderWebhookURL, &http.Client{Timeout: cfg.WebhookTimeout})
+	case "smtp":
+		return reminders.NewSMTPNotifier(cfg.SMTPAddr, cfg.SMTPFrom, cfg.SMTPTo)
+	default:
+		return reminders.LogNotifier{}
+	}
+}
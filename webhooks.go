@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/config"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/repo"
+	"github.com/TheTeemka/task_effective_mobile_subscribe/internal/webhooks"
+)
+
+// startWebhooks runs the webhook dispatcher in the background until ctx is done, unless it is
+// disabled. The returned channel is closed once the dispatcher has stopped.
+func startWebhooks(ctx context.Context, cfg *config.Config, db *sql.DB) <-chan struct{} {
+	done := make(chan struct{})
+	if cfg.WebhookInterval == 0 {
+		slog.Info("Webhook dispatcher disabled")
+		close(done)
+		return done
+	}
+
+	dispatcher := webhooks.NewDispatcher(repo.NewWebhookRepo(db), webhooks.NewClient(cfg.WebhookTimeout), cfg.WebhookInterval, cfg.WebhookMaxAttempts, cfg.WebhookBackoff)
+	slog.Info("Starting webhook dispatcher", "interval", cfg.WebhookInterval, "maxAttempts", cfg.WebhookMaxAttempts, "backoff", cfg.WebhookBackoff)
+	go func() {
+		defer close(done)
+		dispatcher.Run(ctx)
+	}()
+	return done
+}